@@ -0,0 +1,113 @@
+package logberry
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type contextkey struct{}
+
+// States of a Task's concluded field, distinguishing whether it was
+// concluded by its own calls or by its context being done.
+const (
+	taskrunning int32 = iota
+	taskconcluded
+	taskcancelled
+)
+
+// WithTask returns a copy of the given context carrying the Task, to
+// be retrieved by FromContext further down the call chain.
+func WithTask(ctx context.Context, task *Task) context.Context {
+	return context.WithValue(ctx, contextkey{}, task)
+}
+
+// FromContext returns the Task carried by the given context, or Main
+// if there is none.
+func FromContext(ctx context.Context) *Task {
+	if t, ok := TaskFromContext(ctx); ok {
+		return t
+	}
+	return Main
+}
+
+// TaskFromContext returns the Task carried by the given context, and
+// whether or not there was one.
+func TaskFromContext(ctx context.Context) (*Task, bool) {
+	t, ok := ctx.Value(contextkey{}).(*Task)
+	return t, ok && t != nil
+}
+
+// TaskContext creates a new sub-task as Task, bound to the given
+// context.  A copy of the context carrying the new Task is returned
+// alongside it.  If the context is cancelled or its deadline passes
+// before the Task concludes, an error event is generated for the Task
+// reporting the context's cause, after which continuing to use the
+// Task is discouraged and its concluding events are ignored.  The
+// error event does not report the data associated with the Task via
+// AddData, which may be concurrently modified.
+func (x *Task) TaskContext(ctx context.Context, activity string, data ...interface{}) (*Task, context.Context) {
+	t := newtask(nil, x, "", activity, data, nil)
+	return t, t.watch(ctx)
+}
+
+// ComponentContext creates a new component Task as Component, bound to
+// the given context as for TaskContext.
+func (x *Task) ComponentContext(ctx context.Context, component string, data ...interface{}) (*Task, context.Context) {
+	t := newtask(nil, x, component, "Component "+component, data, nil)
+	return t, t.watch(ctx)
+}
+
+// watch concludes the Task with an error if the context is done first,
+// returning a copy of the context carrying the Task.
+func (x *Task) watch(ctx context.Context) context.Context {
+
+	stop := context.AfterFunc(ctx, func() {
+		x.cancel(context.Cause(ctx))
+	})
+
+	x.unwatch.Store(stop)
+
+	return WithTask(ctx, x)
+
+}
+
+// cancel concludes the Task with an error event reporting the cause of
+// its context being done, unless the Task has already concluded.  It
+// runs on a goroutine of its own, and so reads none of the Task's
+// mutable state.
+func (x *Task) cancel(cause error) {
+
+	if !atomic.CompareAndSwapInt32(&x.concluded, taskrunning, taskcancelled) {
+		return
+	}
+
+	if !x.Enabled(LevelError) {
+		return
+	}
+
+	d := EventDataMap{}
+	if c := causedata(cause); c != nil {
+		d["Cause"] = c
+	}
+
+	x.endevent(ERROR, x.activity+" failed", d)
+
+}
+
+// finish marks the Task as concluded, no longer watching any context.
+// It returns false if the Task's context was done first, in which case
+// its concluding event has already been generated.  Tasks may otherwise
+// conclude any number of times.
+func (x *Task) finish() bool {
+
+	if !atomic.CompareAndSwapInt32(&x.concluded, taskrunning, taskconcluded) {
+		return atomic.LoadInt32(&x.concluded) != taskcancelled
+	}
+
+	if stop, ok := x.unwatch.Load().(func() bool); ok {
+		stop()
+	}
+
+	return true
+
+}
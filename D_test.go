@@ -2,7 +2,10 @@ package logberry
 
 import (
 	"bytes"
+	"net"
+	"net/url"
 	"testing"
+	"time"
 )
 
 type d_expectation struct {
@@ -32,6 +35,31 @@ type test struct {
 	IntField    int
 }
 
+type testjson struct{}
+
+func (x testjson) MarshalJSON() ([]byte, error) {
+	return []byte(`{"kind":"json"}`), nil
+}
+
+// testuser is described by its exported fields despite its String
+type testuser struct {
+	ID   int
+	Name string
+}
+
+func (x testuser) String() string {
+	return x.Name
+}
+
+// testopaque has no exported fields, so is described by its String
+type testopaque struct {
+	name string
+}
+
+func (x testopaque) String() string {
+	return x.name
+}
+
 type test2 struct {
 	privatefield string
 	PublicField  int
@@ -113,6 +141,46 @@ func TestCopyFrom(t *testing.T) {
 			v:  D{"baz": []string{"foo", "bar"}},
 			ex: "{ baz=[\"foo\", \"bar\"] }",
 		},
+
+		{
+			v:  time.Date(2020, 4, 1, 12, 30, 0, 5, time.UTC),
+			ex: "2020-04-01T12:30:00.000000005Z",
+		},
+
+		{
+			v:  D{"Elapsed": 1500 * time.Millisecond},
+			ex: "{ Elapsed=1.5s }",
+		},
+
+		{
+			v:  D{"IP": net.IPv4(10, 0, 0, 1)},
+			ex: "{ IP=\"10.0.0.1\" }",
+		},
+
+		{
+			v:  D{"URL": &url.URL{Scheme: "https", Host: "example.com", Path: "/a"}},
+			ex: "{ URL={ ForceQuery=false Host=\"example.com\" OmitHost=false Path=\"/a\" Scheme=\"https\" } }",
+		},
+
+		{
+			v:  testuser{7, "alice"},
+			ex: "{ ID=7 Name=\"alice\" }",
+		},
+
+		{
+			v:  D{"U": testuser{7, "alice"}, "O": testopaque{"bob"}},
+			ex: "{ O=\"bob\" U={ ID=7 Name=\"alice\" } }",
+		},
+
+		{
+			v:  testopaque{"bob"},
+			ex: "\"bob\"",
+		},
+
+		{
+			v:  D{"Level": LevelWarning, "Custom": testjson{}},
+			ex: "{ Custom={\"kind\":\"json\"} Level=\"warning\" }",
+		},
 	}
 
 	runcases_d(tests, t)
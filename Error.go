@@ -2,13 +2,27 @@ package logberry
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime"
+	"strings"
 )
 
+// Position identifies a point in the source code.
 type Position struct {
 	File string
 	Line int
+
+	// The function containing the position, used to trim File when
+	// formatting errors
+	Function string `logberry:"quiet" json:",omitempty"`
+}
+
+// path returns the position's file relative to its module root, as
+// by trimpath.
+func (p *Position) path() string {
+	return trimpath(p.Function, p.File)
 }
 
 // Error captures structured information about a fault.
@@ -26,6 +40,9 @@ type Error struct {
 	// The source code file and line number where the error occurred
 	Source *Position
 
+	// The call stack where the error occurred, if captured
+	Stack Stack
+
 	// Optional link to a preceding error underlying the fault
 	Cause error `logberry:"quiet"`
 
@@ -45,7 +62,8 @@ func wraperror(msg string, err error, data []interface{}) *Error {
 	e := newerror(msg, data)
 	e.Cause = err
 
-	if le, ok := err.(*Error); ok {
+	var le *Error
+	if errors.As(err, &le) {
 		e.Code = le.Code
 	}
 
@@ -59,6 +77,9 @@ func wraperror(msg string, err error, data []interface{}) *Error {
 func NewError(msg string, data ...interface{}) *Error {
 	e := newerror(msg, data)
 	e.Locate(1)
+	if StackTraces() {
+		e.Stack = CaptureStack(1)
+	}
 	return e
 }
 
@@ -70,19 +91,147 @@ func NewError(msg string, data ...interface{}) *Error {
 func WrapError(msg string, err error, data ...interface{}) *Error {
 	e := wraperror(msg, err, data)
 	e.Locate(1)
+	if StackTraces() {
+		e.Stack = CaptureStack(1)
+	}
 	return e
 }
 
+// Format implements fmt.Formatter.  The %v and %s verbs write the
+// message and code of the error and each error it wraps, on one line.
+// The %+v verb writes the error and its cause chain on multiple,
+// indented lines, including the source position, data, and stack
+// trace, if any, of each.  The %#v verb writes a Go-syntax
+// representation of the Error.  Source file paths are written
+// relative to their module's root.
+func (e *Error) Format(f fmt.State, verb rune) {
+
+	switch verb {
+	case 'v':
+		if f.Flag('#') {
+			fmt.Fprintf(f, "&logberry.Error{Code:%#v, Message:%#v, Data:%#v, Source:%#v, Stack:%#v, Cause:%#v, Reported:%#v}",
+				e.Code, e.Message, e.Data, e.Source, e.Stack, e.Cause, e.Reported)
+		} else if f.Flag('+') {
+			e.writechain(f, 0)
+		} else {
+			fmt.Fprint(f, e.short())
+		}
+
+	case 's':
+		fmt.Fprint(f, e.short())
+
+	case 'q':
+		fmt.Fprintf(f, "%q", e.short())
+
+	default:
+		fmt.Fprintf(f, "%%!%c(*logberry.Error=%v)", verb, e.short())
+	}
+
+}
+
+// short returns the message and code of the error and its causes.
+func (e *Error) short() string {
+
+	var buffer = new(bytes.Buffer)
+
+	buffer.WriteString(e.Message)
+
+	if e.Code != "" {
+		fmt.Fprintf(buffer, " <%v>", e.Code)
+	}
+
+	if e.Cause != nil {
+		fmt.Fprintf(buffer, ": %v", e.Cause)
+	}
+
+	return buffer.String()
+
+}
+
+// writechain writes the error and its cause chain, as for %+v, with
+// the given indentation depth.
+func (e *Error) writechain(out fmt.State, depth int) {
+
+	indent := strings.Repeat("    ", depth)
+
+	fmt.Fprintf(out, "%v", e.Message)
+	if e.Code != "" {
+		fmt.Fprintf(out, " <%v>", e.Code)
+	}
+
+	if e.Source != nil {
+		fmt.Fprintf(out, "\n%v    at %v:%v", indent, e.Source.path(), e.Source.Line)
+	}
+
+	if len(e.Data) > 0 {
+		fmt.Fprintf(out, "\n%v    data ", indent)
+		e.Data.WriteTo(out)
+	}
+
+	if len(e.Stack) > 0 {
+		fmt.Fprintf(out, "\n%v    stack", indent)
+		for _, frame := range e.Stack {
+			fmt.Fprintf(out, "\n%v        %v\n%v            %v:%v", indent,
+				frame.Function, indent, trimpath(frame.Function, frame.File), frame.Line)
+		}
+	}
+
+	writecause(out, e.Cause, depth+1)
+
+}
+
+// writecause writes the given cause, and the errors it wraps, as for
+// %+v.  Standard wrapping errors are written without the text of the
+// errors they wrap, where that is simply appended to their own.
+func writecause(out fmt.State, cause error, depth int) {
+
+	if cause == nil {
+		return
+	}
+
+	indent := strings.Repeat("    ", depth)
+	fmt.Fprintf(out, "\n%vcaused by: ", indent)
+
+	switch c := cause.(type) {
+
+	case *Error:
+		c.writechain(out, depth)
+
+	case interface{ Unwrap() error }:
+		msg := cause.Error()
+		if inner := c.Unwrap(); inner != nil {
+			msg = strings.TrimSuffix(msg, ": "+inner.Error())
+			msg = strings.TrimSuffix(msg, fmt.Sprintf(": %v", inner))
+		}
+		fmt.Fprint(out, msg)
+		writecause(out, c.Unwrap(), depth+1)
+
+	case interface{ Unwrap() []error }:
+		fmt.Fprintf(out, "%v errors", len(c.Unwrap()))
+		for _, inner := range c.Unwrap() {
+			writecause(out, inner, depth+1)
+		}
+
+	default:
+		fmt.Fprint(out, cause.Error())
+
+	}
+
+}
+
 // Locate sets the source code position to be reported with this error
 // as that point where the Locate call is made.  It should not
 // generally be necessary to invoke this manually when using Logberry.
 func (e *Error) Locate(skip int) {
-	_, file, line, ok := runtime.Caller(skip + 1)
+	pc, file, line, ok := runtime.Caller(skip + 1)
 	if ok {
 		e.Source = &Position{
 			File: file,
 			Line: line,
 		}
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			e.Source.Function = fn.Name()
+		}
 	}
 }
 
@@ -92,28 +241,144 @@ func (e *Error) SetCode(code string) *Error {
 	return e
 }
 
-// IsError checks if the given error is a Logberry Error tagged with
-// any of the given codes, returning true if so and false otherwise.
+// IsError checks if the given error, or any error it wraps, is a
+// Logberry Error tagged with any of the given codes, returning true if
+// so and false otherwise.  Wrapped errors are found as by errors.Is,
+// including through fmt.Errorf and errors.Join wrappers.
 func IsError(e error, code ...string) bool {
 
-	err, ok := e.(*Error)
-	if !ok {
+	found := false
+
+	walkerrors(e, func(err error) bool {
+		le, ok := err.(*Error)
+		if !ok {
+			return true
+		}
+
+		for _, c := range code {
+			if le.Code == c {
+				found = true
+				return false
+			}
+		}
+
+		return true
+	})
+
+	return found
+
+}
+
+// walkerrors calls visit on each error in the tree of errors wrapped
+// by err, depth first, until visit returns false.  It returns false
+// if the walk was stopped.
+func walkerrors(err error, visit func(error) bool) bool {
+
+	if err == nil {
+		return true
+	}
+
+	if !visit(err) {
 		return false
 	}
 
-	for _, c := range code {
-		if err.Code == c {
-			return true
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return walkerrors(u.Unwrap(), visit)
+
+	case interface{ Unwrap() []error }:
+		for _, c := range u.Unwrap() {
+			if !walkerrors(c, visit) {
+				return false
+			}
+		}
+	}
+
+	return true
+
+}
+
+// Unwrap returns the error's cause, if any, such that Errors
+// participate in the standard errors.Is and errors.As functions.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether the error matches the target, for use by
+// errors.Is.  Besides being identical, an Error matches a target Error
+// that has the same non-empty Code, e.g.:
+//
+//	errors.Is(err, &logberry.Error{Code: "not-found"})
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// causedata returns the data reporting the chain of errors wrapped by
+// the given cause, marking Logberry Errors as reported.  Errors that
+// have already been reported, and the errors they wrap, are omitted,
+// returning nil if the cause itself has been.  Standard wrapping
+// errors, e.g., from fmt.Errorf, are reported with their own data and
+// the chain continued as Cause, or as Causes for multiple wrapped
+// errors as from errors.Join.
+func causedata(cause error) EventData {
+
+	switch e := cause.(type) {
+
+	case nil:
+		return nil
+
+	case *Error:
+		if e.Reported {
+			return nil
+		}
+		e.Reported = true
+
+		d := EventDataMap{}.Aggregate(e)
+		if c := causedata(e.Cause); c != nil {
+			d["Cause"] = c
+		}
+		return d
+
+	case interface{ Unwrap() error }:
+		d := errordata(cause)
+		if c := causedata(e.Unwrap()); c != nil {
+			d["Cause"] = c
+		}
+		return d
+
+	case interface{ Unwrap() []error }:
+		d := errordata(cause)
+		causes := EventDataSlice{}
+		for _, c := range e.Unwrap() {
+			if cd := causedata(c); cd != nil {
+				causes = append(causes, cd)
+			}
+		}
+		if len(causes) > 0 {
+			d["Causes"] = causes
 		}
+		return d
+
+	default:
+		return Copy(cause)
+
 	}
 
-	return false
+}
 
+// errordata copies a standard error into a map, such that its cause
+// may be added.
+func errordata(err error) EventDataMap {
+	if d, ok := Copy(err).(EventDataMap); ok && d != nil {
+		return d
+	}
+	return EventDataMap{"Error()": EventDataString(err.Error())}
 }
 
-// Error returns a human-oriented serialization of the error.  It does
-// not report the wrapped cause, if any.  That must be retrieved and
-// reported manually.
+// Error returns a human-oriented serialization of the error, its
+// code, source position, and data, followed by that of its cause, if
+// any.  See Format for more concise and more readable alternatives.
 func (e *Error) Error() string {
 
 	var buffer = new(bytes.Buffer)
@@ -125,7 +390,7 @@ func (e *Error) Error() string {
 	}
 
 	if e.Source != nil {
-		fmt.Fprintf(buffer, " [%v:%v]", e.Source.File, e.Source.Line)
+		fmt.Fprintf(buffer, " [%v:%v]", e.Source.path(), e.Source.Line)
 	}
 
 	if e.Data != nil && len(e.Data) > 0 {
@@ -140,6 +405,207 @@ func (e *Error) Error() string {
 	return buffer.String()
 }
 
+// errorjson is the JSON encoding of an Error.
+type errorjson struct {
+	Code    string `json:",omitempty"`
+	Message string
+	Data    EventDataMap    `json:",omitempty"`
+	Source  *Position       `json:",omitempty"`
+	Stack   Stack           `json:",omitempty"`
+	Cause   json.RawMessage `json:",omitempty"`
+}
+
+// wrapperjson is the JSON encoding of a standard wrapping error, e.g.,
+// from fmt.Errorf or errors.Join, as a cause.
+type wrapperjson struct {
+	Text   *string
+	Cause  json.RawMessage   `json:",omitempty"`
+	Causes []json.RawMessage `json:",omitempty"`
+}
+
+// MarshalJSON encodes the error and its cause chain as JSON, such that
+// it may be reconstructed by UnmarshalJSON, e.g., in another service.
+// Standard wrapping errors in the chain, e.g., from fmt.Errorf and
+// errors.Join, are encoded as their text and the errors they wrap.
+// Other causes that are not Logberry Errors are encoded as their
+// text, ending the chain.  Whether or not the error has been reported
+// is not encoded.
+func (e *Error) MarshalJSON() ([]byte, error) {
+
+	j := errorjson{
+		Code:    e.Code,
+		Message: e.Message,
+		Data:    e.Data,
+		Source:  e.Source,
+		Stack:   e.Stack,
+	}
+
+	if e.Cause != nil {
+		b, err := marshalcause(e.Cause)
+		if err != nil {
+			return nil, err
+		}
+		j.Cause = b
+	}
+
+	return json.Marshal(&j)
+
+}
+
+// marshalcause encodes an error in a cause chain.
+func marshalcause(cause error) (json.RawMessage, error) {
+
+	switch c := cause.(type) {
+
+	case *Error:
+		return json.Marshal(c)
+
+	case interface{ Unwrap() error }:
+		text := cause.Error()
+		j := wrapperjson{Text: &text}
+		if inner := c.Unwrap(); inner != nil {
+			b, err := marshalcause(inner)
+			if err != nil {
+				return nil, err
+			}
+			j.Cause = b
+		}
+		return json.Marshal(&j)
+
+	case interface{ Unwrap() []error }:
+		text := cause.Error()
+		j := wrapperjson{Text: &text}
+		for _, inner := range c.Unwrap() {
+			b, err := marshalcause(inner)
+			if err != nil {
+				return nil, err
+			}
+			j.Causes = append(j.Causes, b)
+		}
+		return json.Marshal(&j)
+
+	default:
+		return json.Marshal(cause.Error())
+
+	}
+
+}
+
+// UnmarshalJSON decodes an error and its cause chain as encoded by
+// MarshalJSON.  Causes encoded as text are reconstructed as errors
+// with that text, as from errors.New.  Standard wrapping errors are
+// reconstructed as errors with their text wrapping their decoded
+// causes, such that errors.Is, errors.As, and IsError still find the
+// Logberry Errors they wrap.  Data is reconstructed as by
+// EventDataMap's UnmarshalJSON.
+func (e *Error) UnmarshalJSON(b []byte) error {
+
+	var j errorjson
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	*e = Error{
+		Code:    j.Code,
+		Message: j.Message,
+		Data:    j.Data,
+		Source:  j.Source,
+		Stack:   j.Stack,
+	}
+
+	cause, err := unmarshalcause(j.Cause)
+	if err != nil {
+		return err
+	}
+	e.Cause = cause
+
+	return nil
+
+}
+
+// unmarshalcause decodes an error in a cause chain, returning nil if
+// there is none.
+func unmarshalcause(b json.RawMessage) (error, error) {
+
+	b = bytes.TrimSpace(b)
+
+	if len(b) == 0 || bytes.Equal(b, []byte("null")) {
+		return nil, nil
+	}
+
+	if b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return nil, err
+		}
+		return errors.New(s), nil
+	}
+
+	var w wrapperjson
+	if err := json.Unmarshal(b, &w); err != nil {
+		return nil, err
+	}
+
+	if w.Text == nil {
+		c := &Error{}
+		if err := json.Unmarshal(b, c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	if w.Causes != nil {
+		j := &joinederror{text: *w.Text}
+		for _, cb := range w.Causes {
+			c, err := unmarshalcause(cb)
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				j.causes = append(j.causes, c)
+			}
+		}
+		return j, nil
+	}
+
+	c, err := unmarshalcause(w.Cause)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappederror{text: *w.Text, cause: c}, nil
+
+}
+
+// wrappederror is a standard wrapping error, e.g., from fmt.Errorf,
+// reconstructed from JSON.
+type wrappederror struct {
+	text  string
+	cause error
+}
+
+func (e *wrappederror) Error() string {
+	return e.text
+}
+
+func (e *wrappederror) Unwrap() error {
+	return e.cause
+}
+
+// joinederror is a standard error wrapping several others, e.g., from
+// errors.Join, reconstructed from JSON.
+type joinederror struct {
+	text   string
+	causes []error
+}
+
+func (e *joinederror) Error() string {
+	return e.text
+}
+
+func (e *joinederror) Unwrap() []error {
+	return e.causes
+}
+
 // String returns a human-oriented serialization of the error.  It is
 // the same as Error().
 func (e *Error) String() string {
@@ -0,0 +1,248 @@
+package logberry
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrorCode defines a class of errors, identified by the Code of the
+// Errors in that class.  ErrorCodes are registered to a catalog via
+// RegisterCode, typically as package variables, e.g.:
+//
+//	var ErrNotFound = logberry.RegisterCode(logberry.ErrorCode{
+//		Code:    "not-found",
+//		Message: "User {User} not found",
+//		Level:   logberry.LevelWarning,
+//		Status:  http.StatusNotFound,
+//	})
+//
+//	return ErrNotFound.New(logberry.D{"User": id})
+//
+// As errors are not traces, a Level of zero, i.e., LevelTrace, is
+// taken to be unset and registered as LevelError.  ErrorCodes may thus
+// not be registered with LevelTrace, and the ErrorCode returned by
+// RegisterCode may differ from that given in its Level.  Similarly, a
+// Status of zero is answered as internal server error.
+type ErrorCode struct {
+
+	// The identifier for the class, set as the Code of its Errors
+	Code string
+
+	// The default message template for Errors of this class.  Each
+	// {Key} in the template is replaced by the Data value of that key.
+	Message string
+
+	// The severity of Errors of this class, LevelError if zero
+	Level Level
+
+	// The HTTP status code with which Errors of this class should be
+	// answered, or zero for internal server error
+	Status int
+
+	// Whether or not operations failing with this class of error may
+	// be retried
+	Retryable bool
+
+	// Optional documentation for this class of errors
+	URL string
+}
+
+var catalog = struct {
+	sync.RWMutex
+	codes map[string]*ErrorCode
+}{codes: make(map[string]*ErrorCode)}
+
+// RegisterCode adds the given definition to the catalog of error
+// codes, returning the registered ErrorCode, with an unset Level
+// registered as LevelError.  It panics if the code is empty or has
+// already been registered, as that is a programming error generally
+// made at initialization.
+func RegisterCode(code ErrorCode) *ErrorCode {
+
+	if code.Code == "" {
+		panic("logberry: registering empty error code")
+	}
+
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	if _, ok := catalog.codes[code.Code]; ok {
+		panic("logberry: error code registered twice: " + code.Code)
+	}
+
+	c := &code
+	if c.Level == LevelTrace {
+		c.Level = LevelError
+	}
+	catalog.codes[c.Code] = c
+	return c
+
+}
+
+// LookupCode returns the registered definition of the given code, and
+// whether or not it was found.
+func LookupCode(code string) (*ErrorCode, bool) {
+	catalog.RLock()
+	c, ok := catalog.codes[code]
+	catalog.RUnlock()
+	return c, ok
+}
+
+// Codes returns all registered error codes, sorted by Code.
+func Codes() []*ErrorCode {
+
+	catalog.RLock()
+	codes := make([]*ErrorCode, 0, len(catalog.codes))
+	for _, c := range catalog.codes {
+		codes = append(codes, c)
+	}
+	catalog.RUnlock()
+
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+
+	return codes
+
+}
+
+// New generates a new Error of this class, with its message expanded
+// from the ErrorCode's template and the given data.  The source code
+// position to be reported by this Error is the point at which New was
+// called.
+func (c *ErrorCode) New(data ...interface{}) *Error {
+	e := newerror("", data)
+	e.Message = c.expand(e.Data)
+	e.Code = c.Code
+	e.Locate(1)
+	if StackTraces() {
+		e.Stack = CaptureStack(1)
+	}
+	return e
+}
+
+// Wrap generates a new Error of this class wrapping the given cause,
+// as WrapError but with its message expanded from the ErrorCode's
+// template and the given data.
+func (c *ErrorCode) Wrap(err error, data ...interface{}) *Error {
+	e := wraperror("", err, data)
+	e.Message = c.expand(e.Data)
+	e.Code = c.Code
+	e.Locate(1)
+	if StackTraces() {
+		e.Stack = CaptureStack(1)
+	}
+	return e
+}
+
+// Is reports whether the given error, or any error it wraps, is of
+// this class, as by IsError.
+func (c *ErrorCode) Is(err error) bool {
+	return IsError(err, c.Code)
+}
+
+// expand replaces each {Key} in the message template with the value of
+// that key in the data, leaving unknown keys as they are.
+func (c *ErrorCode) expand(data EventDataMap) string {
+
+	var buffer = new(bytes.Buffer)
+
+	msg := c.Message
+	for {
+		open := strings.Index(msg, "{")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(msg[open:], "}")
+		if end < 0 {
+			break
+		}
+		end += open
+
+		buffer.WriteString(msg[:open])
+
+		switch v := data[msg[open+1:end]].(type) {
+		case nil:
+			buffer.WriteString(msg[open : end+1])
+		case EventDataString:
+			buffer.WriteString(string(v))
+		default:
+			v.WriteTo(buffer)
+		}
+
+		msg = msg[end+1:]
+	}
+
+	buffer.WriteString(msg)
+
+	return buffer.String()
+
+}
+
+// ErrorCodeOf returns the definition of the first registered code
+// among the given error and the errors it wraps, or nil if there is
+// none.
+func ErrorCodeOf(err error) *ErrorCode {
+
+	var code *ErrorCode
+
+	walkerrors(err, func(err error) bool {
+		if le, ok := err.(*Error); ok && le.Code != "" {
+			if c, ok := LookupCode(le.Code); ok {
+				code = c
+				return false
+			}
+		}
+		return true
+	})
+
+	return code
+
+}
+
+// ErrorStatus returns the HTTP status code registered for the given
+// error's code, as found by ErrorCodeOf, or internal server error if
+// none is registered.
+func ErrorStatus(err error) int {
+	if c := ErrorCodeOf(err); c != nil && c.Status != 0 {
+		return c.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorSeverity returns the Level registered for the given error's
+// code, as found by ErrorCodeOf, or LevelError if none is registered.
+func ErrorSeverity(err error) Level {
+	if c := ErrorCodeOf(err); c != nil {
+		return c.Level
+	}
+	return LevelError
+}
+
+// IsRetryable reports whether the given error's code, as found by
+// ErrorCodeOf, is registered as retryable.
+func IsRetryable(err error) bool {
+	c := ErrorCodeOf(err)
+	return c != nil && c.Retryable
+}
+
+// UnknownCodes returns the codes of the given error and the errors it
+// wraps that are not registered, e.g., to validate that logged errors
+// are cataloged.
+func UnknownCodes(err error) []string {
+
+	var unknown []string
+
+	walkerrors(err, func(err error) bool {
+		if le, ok := err.(*Error); ok && le.Code != "" {
+			if _, ok := LookupCode(le.Code); !ok {
+				unknown = append(unknown, le.Code)
+			}
+		}
+		return true
+	})
+
+	return unknown
+
+}
@@ -0,0 +1,94 @@
+package logberry
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sort"
+	"testing"
+)
+
+var (
+	testnotfound = RegisterCode(ErrorCode{
+		Code:    "test-not-found",
+		Message: "User {User} not found in {Table} {Missing}",
+		Level:   LevelWarning,
+		Status:  http.StatusNotFound,
+		URL:     "https://example.com/errors/not-found",
+	})
+
+	testunavailable = RegisterCode(ErrorCode{
+		Code:      "test-unavailable",
+		Message:   "Service unavailable",
+		Status:    http.StatusServiceUnavailable,
+		Retryable: true,
+	})
+)
+
+func Test_ErrorCode_New(t *testing.T) {
+	require := require.New(t)
+
+	e := testnotfound.New(D{"User": 7, "Table": "users"})
+	require.Equal("test-not-found", e.Code)
+	require.Equal("User 7 not found in users {Missing}", e.Message)
+	require.Equal(EventDataInt64(7), e.Data["User"])
+	require.NotNil(e.Source)
+
+	base := errors.New("connection refused")
+	w := testunavailable.Wrap(fmt.Errorf("dialing: %w", base))
+	require.Equal("test-unavailable", w.Code)
+	require.Equal("Service unavailable", w.Message)
+	require.True(errors.Is(w, base))
+	require.True(testunavailable.Is(fmt.Errorf("fetching: %w", w)))
+	require.False(testnotfound.Is(w))
+
+	require.Panics(func() { RegisterCode(ErrorCode{Code: "test-not-found"}) })
+	require.Panics(func() { RegisterCode(ErrorCode{}) })
+
+}
+
+func Test_ErrorCode_Catalog(t *testing.T) {
+	require := require.New(t)
+
+	c, ok := LookupCode("test-unavailable")
+	require.True(ok)
+	require.Equal(testunavailable, c)
+	require.Equal(LevelError, c.Level)
+
+	_, ok = LookupCode("test-unknown")
+	require.False(ok)
+
+	var found []string
+	for _, c := range Codes() {
+		found = append(found, c.Code)
+	}
+	require.Subset(found, []string{"test-not-found", "test-unavailable"})
+	require.True(sort.StringsAreSorted(found))
+
+}
+
+func Test_ErrorCode_Classification(t *testing.T) {
+	require := require.New(t)
+
+	e := WrapError("Could not load profile", testunavailable.New()).SetCode("test-unknown")
+
+	require.Equal(testunavailable, ErrorCodeOf(e))
+	require.Equal(http.StatusServiceUnavailable, ErrorStatus(e))
+	require.Equal(LevelError, ErrorSeverity(e))
+	require.True(IsRetryable(e))
+	require.Equal([]string{"test-unknown"}, UnknownCodes(e))
+
+	n := testnotfound.New()
+	require.Equal(http.StatusNotFound, ErrorStatus(n))
+	require.Equal(LevelWarning, ErrorSeverity(n))
+	require.False(IsRetryable(n))
+	require.Nil(UnknownCodes(n))
+
+	plain := errors.New("plain")
+	require.Nil(ErrorCodeOf(plain))
+	require.Equal(http.StatusInternalServerError, ErrorStatus(plain))
+	require.Equal(LevelError, ErrorSeverity(plain))
+	require.False(IsRetryable(plain))
+
+}
@@ -14,8 +14,27 @@ type ErrorListener interface {
 	Error(err error)
 }
 
+// A ListenerHandle identifies an ErrorListener registered to a Root.
+type ListenerHandle struct {
+	root     *Root
+	listener ErrorListener
+}
+
+// Listener returns the ErrorListener registered under this handle.
+func (x *ListenerHandle) Listener() ErrorListener {
+	return x.listener
+}
+
+// Remove unregisters the listener from its Root.  It is identical to
+// calling RemoveErrorListener on the Root.
+func (x *ListenerHandle) Remove() {
+	x.root.RemoveErrorListener(x)
+}
+
+// StdErrorListener prints internal errors to standard output, with
+// their full cause chains as by the %+v verb.
 type StdErrorListener struct{}
 
 func (x *StdErrorListener) Error(err error) {
-	fmt.Println(err)
+	fmt.Printf("%+v\n", err)
 }
@@ -0,0 +1,155 @@
+package logberry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_Error_Wrapping(t *testing.T) {
+	require := require.New(t)
+
+	base := errors.New("connection refused")
+	lerr := WrapError("Could not connect", base).SetCode("unavailable")
+	wrapped := fmt.Errorf("fetching user: %w", lerr)
+	joined := errors.Join(errors.New("unrelated"), wrapped)
+
+	require.True(errors.Is(lerr, base))
+	require.True(errors.Is(joined, base))
+	require.True(errors.Is(joined, &Error{Code: "unavailable"}))
+	require.False(errors.Is(joined, &Error{Code: "not-found"}))
+	require.False(errors.Is(joined, &Error{}))
+
+	var target *Error
+	require.True(errors.As(wrapped, &target))
+	require.Equal(lerr, target)
+
+	require.True(IsError(joined, "not-found", "unavailable"))
+	require.False(IsError(joined, "not-found"))
+	require.False(IsError(base, "unavailable"))
+
+	// Codes are inherited through standard wrappers
+	require.Equal("unavailable", WrapError("Could not load profile", wrapped).Code)
+
+}
+
+func Test_Error_CauseChain(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	inner := NewError("Disk full")
+	wrapped := fmt.Errorf("writing cache: %w", inner)
+
+	task := root.Task("Save")
+	task.Error(wrapped)
+
+	root.Stop()
+
+	cause := capture.events[1].Data["Cause"].(EventDataMap)
+	require.Contains(string(cause["Error()"].(EventDataString)), "writing cache: Disk full")
+
+	next := cause["Cause"].(EventDataMap)
+	require.Equal(EventDataString("Disk full"), next["Message"])
+	require.True(inner.Reported)
+
+}
+
+func Test_Error_Format(t *testing.T) {
+	require := require.New(t)
+
+	base := errors.New("connection refused")
+	inner := WrapError("Could not connect", base, D{"Port": 80}).SetCode("unavailable")
+	outer := WrapError("Could not load profile", fmt.Errorf("fetching user: %w", inner))
+
+	require.Equal("Could not load profile <unavailable>: fetching user: Could not connect <unavailable>: connection refused", fmt.Sprintf("%v", outer))
+	require.Equal(fmt.Sprintf("%v", outer), fmt.Sprintf("%s", outer))
+	require.Equal(`"Could not connect <unavailable>: connection refused"`, fmt.Sprintf("%q", inner))
+
+	require.Regexp(`^Could not load profile <unavailable>
+    at Error_test.go:\d+
+    caused by: fetching user
+        caused by: Could not connect <unavailable>
+            at Error_test.go:\d+
+            data { Port=80 }
+            caused by: connection refused$`, fmt.Sprintf("%+v", outer))
+
+	require.Regexp(`^&logberry.Error{Code:"unavailable", Message:"Could not connect", Data:logberry.EventDataMap{"Port":80}, Source:&logberry.Position{File:".*Error_test.go", Line:\d+, Function:".*Test_Error_Format"}, Stack:logberry.Stack\(nil\), Cause:&errors.errorString{s:"connection refused"}, Reported:false}$`, fmt.Sprintf("%#v", inner))
+
+	require.Regexp(`^Could not connect <unavailable> \[Error_test.go:\d+\] { Port=80 }: connection refused$`, inner.Error())
+
+}
+
+func Test_Error_TrimPath(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("Error.go", trimpath("github.com/BellerophonMobile/logberry.NewError", "/src/logberry/Error.go"))
+	require.Equal("httplog/Handler.go", trimpath("github.com/BellerophonMobile/logberry/httplog.(*ResponseWriter).Write", "/src/logberry/httplog/Handler.go"))
+	require.Equal("net/http/server.go", trimpath("net/http.(*conn).serve", "/usr/lib/go/src/net/http/server.go"))
+	require.Equal("main.go", trimpath("main.main.func1", "/src/app/main.go"))
+	require.Equal("/src/x.go", trimpath("", "/src/x.go"))
+
+}
+
+func Test_Error_JSON(t *testing.T) {
+	require := require.New(t)
+
+	base := errors.New("connection refused")
+	inner := WrapError("Could not connect", base, D{"Port": 80, "Ratio": 0.5, "Hosts": []string{"a", "b"}}).SetCode("unavailable")
+	inner.Stack = CaptureStack(0)
+	outer := WrapError("Could not load profile", inner)
+	outer.Reported = true
+
+	b, err := json.Marshal(outer)
+	require.Nil(err)
+
+	var decoded Error
+	require.Nil(json.Unmarshal(b, &decoded))
+
+	require.Equal("Could not load profile", decoded.Message)
+	require.Equal("unavailable", decoded.Code)
+	require.Equal(*outer.Source, *decoded.Source)
+	require.False(decoded.Reported)
+
+	cause, ok := decoded.Cause.(*Error)
+	require.True(ok)
+	require.Equal(inner.Message, cause.Message)
+	require.Equal(inner.Stack, cause.Stack)
+	require.Equal(EventDataMap{
+		"Port":  EventDataInt64(80),
+		"Ratio": EventDataFloat64(0.5),
+		"Hosts": EventDataSlice{EventDataString("a"), EventDataString("b")},
+	}, cause.Data)
+	require.Equal("connection refused", cause.Cause.Error())
+
+	require.Equal(fmt.Sprint(outer), fmt.Sprint(&decoded))
+
+	// Other causes are encoded as text
+	b, err = json.Marshal(WrapError("Could not save", base))
+	require.Nil(err)
+	require.Contains(string(b), `"Cause":"connection refused"`)
+
+	// Standard wrappers are encoded along with the chain they wrap
+	coded := NewError("Disk full").SetCode("no-space")
+	chained := WrapError("Could not save", errors.Join(errors.New("unrelated"),
+		fmt.Errorf("writing: %w", coded)))
+
+	b, err = json.Marshal(chained)
+	require.Nil(err)
+
+	var remote Error
+	require.Nil(json.Unmarshal(b, &remote))
+	require.True(IsError(&remote, "no-space"))
+	require.True(errors.Is(&remote, &Error{Code: "no-space"}))
+	require.Equal(chained.Cause.Error(), remote.Cause.Error())
+	require.Equal(fmt.Sprintf("%+v", chained), fmt.Sprintf("%+v", &remote))
+
+	b2, err := json.Marshal(&remote)
+	require.Nil(err)
+	require.JSONEq(string(b), string(b2))
+
+}
@@ -16,18 +16,43 @@ const (
 	SUCCESS       string = "success"
 	WARNING       string = "warning"
 	ERROR         string = "error"
+	DEBUG         string = "debug"
+	TRACE         string = "trace"
 )
 
 // Event captures an annotated occurrence or message, a log entry.
 type Event struct {
-	TaskID   uint64
-	ParentID uint64
+	TaskID   ID
+	ParentID ID `json:",omitempty"`
+
+	// RunID identifies the execution of the program generating the
+	// event, see the RunID variable.
+	RunID ID
+
+	// TraceID, SpanID, and ParentSpanID place the generating Task in a
+	// distributed trace, if it is part of one.  They are formatted as
+	// in the W3C traceparent header.  ParentSpanID may identify a span
+	// in another process.
+	TraceID      string `json:",omitempty"`
+	SpanID       string `json:",omitempty"`
+	ParentSpanID string `json:",omitempty"`
 
 	Component string
 
 	Event   string
+	Level   Level
 	Message string
 	Data    EventDataMap
 
+	// Static is data configured on the Root and attached to all of its
+	// events, e.g., identifying the program and host.  It is shared
+	// among events and must not be modified.
+	Static EventDataMap `json:",omitempty"`
+
 	Timestamp time.Time
+
+	// Duration is the time elapsed since the generating Task began.
+	// It is only set on events concluding a Task, i.e., success,
+	// error, end, and stopped events.
+	Duration time.Duration `json:",omitempty"`
 }
@@ -2,11 +2,15 @@ package logberry
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // A DBuilder is a type that can return logberry data when logged.
@@ -26,6 +30,19 @@ type EventDataUInt64 uint64
 type EventDataFloat64 float64
 type EventDataBool bool
 
+// EventDataTime is a timestamp.  It is written as RFC 3339 text with
+// nanosecond precision, and likewise as a JSON string.
+type EventDataTime time.Time
+
+// EventDataDuration is an elapsed time.  It is written in the form of
+// time.Duration.String, e.g., "1.5s", and likewise as a JSON string.
+type EventDataDuration time.Duration
+
+// EventDataJSON is the output of a json.Marshaler, captured when the
+// data was copied.  It is written as the JSON text itself, and
+// embedded as such in JSON.
+type EventDataJSON []byte
+
 func (x EventDataMap) String() string {
 	buff := new(bytes.Buffer)
 	x.WriteTo(buff)
@@ -100,6 +117,98 @@ func (x EventDataBool) WriteTo(out io.Writer) {
 	fmt.Fprintf(out, "%v", x)
 }
 
+func (x EventDataTime) WriteTo(out io.Writer) {
+	fmt.Fprintf(out, "%v", time.Time(x).Format(time.RFC3339Nano))
+}
+
+func (x EventDataTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(x).Format(time.RFC3339Nano))
+}
+
+func (x EventDataDuration) WriteTo(out io.Writer) {
+	fmt.Fprintf(out, "%v", time.Duration(x))
+}
+
+func (x EventDataDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(x).String())
+}
+
+func (x EventDataJSON) WriteTo(out io.Writer) {
+	out.Write(x)
+}
+
+func (x EventDataJSON) MarshalJSON() ([]byte, error) {
+	return x, nil
+}
+
+// UnmarshalJSON decodes a JSON object into the map, such that event
+// data may be reconstructed from its JSON encoding.  Numbers are
+// decoded as EventDataInt64 or EventDataUInt64 if integral and
+// EventDataFloat64 otherwise, and null values as nil maps.  Types not
+// distinguished by JSON, e.g., times, are decoded as their encodings.
+func (x *EventDataMap) UnmarshalJSON(b []byte) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var v map[string]interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+
+	if v == nil {
+		*x = nil
+		return nil
+	}
+
+	*x = fromjson(v).(EventDataMap)
+	return nil
+
+}
+
+// fromjson converts a value decoded from JSON, with numbers decoded
+// as json.Number, into event data.
+func fromjson(v interface{}) EventData {
+
+	switch t := v.(type) {
+
+	case map[string]interface{}:
+		m := make(EventDataMap, len(t))
+		for k, e := range t {
+			m[k] = fromjson(e)
+		}
+		return m
+
+	case []interface{}:
+		s := make(EventDataSlice, len(t))
+		for i, e := range t {
+			s[i] = fromjson(e)
+		}
+		return s
+
+	case string:
+		return EventDataString(t)
+
+	case bool:
+		return EventDataBool(t)
+
+	case json.Number:
+		if i, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+			return EventDataInt64(i)
+		}
+		if u, err := strconv.ParseUint(string(t), 10, 64); err == nil {
+			return EventDataUInt64(u)
+		}
+		f, _ := t.Float64()
+		return EventDataFloat64(f)
+
+	default:
+		return EventDataMap(nil)
+
+	}
+
+}
+
 /*
 func MakeEventData(data []interface{}) EventData {
 
@@ -142,6 +251,16 @@ func copy(data interface{}) (EventData, bool) {
 		return EventDataMap(nil), true
 	}
 
+	if v, zero, ok := copyspecial(data); ok {
+		return v, zero
+	}
+
+	if opaque(val) {
+		if v, zero, ok := copymarshaled(data); ok {
+			return v, zero
+		}
+	}
+
 	zero := true
 
 	switch val.Kind() {
@@ -184,6 +303,18 @@ func (x EventDataMap) Aggregate(data interface{}) EventDataMap {
 		return x
 	}
 
+	v, zero, ok := copyspecial(data)
+	if !ok && opaque(val) {
+		v, zero, ok = copymarshaled(data)
+	}
+
+	if ok {
+		if !zero {
+			x.aggregatevalue(v)
+		}
+		return x
+	}
+
 	switch val.Kind() {
 
 	case reflect.Struct:
@@ -199,23 +330,109 @@ func (x EventDataMap) Aggregate(data interface{}) EventDataMap {
 			break
 		}
 
-		prev, find := x["value"]
+		x.aggregatevalue(newval)
+
+	}
+
+	return x
+
+}
+
+func (x EventDataMap) aggregatevalue(newval EventData) {
+
+	prev, find := x["value"]
+
+	if find {
+		switch p := prev.(type) {
+		case EventDataSlice:
+			x["value"] = append(p, newval)
+
+		default:
+			x["value"] = EventDataSlice{p, newval}
+		}
+	} else {
+		x["value"] = newval
+	}
+
+}
+
+// copyspecial converts values of types that are better represented
+// by their own serializations than by reflecting over their fields,
+// returning false if the data is not one of them.  Timestamps,
+// durations, and stacks are captured natively.
+func copyspecial(data interface{}) (EventData, bool, bool) {
+
+	switch v := data.(type) {
+	case time.Time:
+		return EventDataTime(v), v.IsZero(), true
+	case *time.Time:
+		return EventDataTime(*v), v.IsZero(), true
+	case time.Duration:
+		return EventDataDuration(v), v == 0, true
+	case *time.Duration:
+		return EventDataDuration(*v), *v == 0, true
+	case Stack:
+		return v, len(v) == 0, true
+	}
+
+	return nil, false, false
+
+}
 
-		if find {
-			switch p := prev.(type) {
-			case EventDataSlice:
-				x["value"] = append(p, newval)
+// opaque reports whether reflection over the given value captures no
+// meaningful data, i.e., it is a struct without exported fields, or
+// is not a struct or map and so is captured as its raw
+// representation, e.g., an enumeration's number or an address's bytes.
+func opaque(val reflect.Value) bool {
 
-			default:
-				x["value"] = EventDataSlice{p, newval}
+	switch val.Kind() {
+	case reflect.Struct:
+		t := val.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath == "" {
+				return false
 			}
-		} else {
-			x["value"] = newval
 		}
+		return true
 
+	case reflect.Map:
+		return false
 	}
 
-	return x
+	return true
+
+}
+
+// copymarshaled converts opaque values via their own serializations,
+// returning false if the data has none.  The json.Marshaler,
+// encoding.TextMarshaler, and fmt.Stringer interfaces are used in that
+// order of preference.  Errors and EventData are not converted, being
+// handled by the general copy.
+func copymarshaled(data interface{}) (EventData, bool, bool) {
+
+	switch data.(type) {
+	case error, EventData:
+		return nil, false, false
+	}
+
+	if m, ok := data.(json.Marshaler); ok {
+		if b, err := m.MarshalJSON(); err == nil {
+			return EventDataJSON(b), false, true
+		}
+	}
+
+	if m, ok := data.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return EventDataString(b), len(b) == 0, true
+		}
+	}
+
+	if s, ok := data.(fmt.Stringer); ok {
+		str := s.String()
+		return EventDataString(str), str == "", true
+	}
+
+	return nil, false, false
 
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func Test_EventData_NilMap(t *testing.T) {
@@ -89,3 +90,41 @@ func Test_EventData_Slice(t *testing.T) {
 	require.Equal(test, buff.String())
 
 }
+
+func Test_EventData_TimeJSON(t *testing.T) {
+	require := require.New(t)
+
+	data := Aggregate([]interface{}{D{
+		"At":      time.Date(2020, 4, 1, 12, 30, 0, 0, time.UTC),
+		"Elapsed": 90 * time.Second,
+	}})
+
+	b, err := json.Marshal(data)
+	require.Nil(err)
+
+	require.Equal(`{"At":"2020-04-01T12:30:00Z","Elapsed":"1m30s"}`, string(b))
+
+	data = Aggregate([]interface{}{time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)})
+	require.Equal(EventDataTime(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)), data["value"])
+
+}
+
+func Test_EventDataMap_UnmarshalJSON(t *testing.T) {
+	require := require.New(t)
+
+	var m EventDataMap
+	require.Nil(json.Unmarshal([]byte(`{"a":1,"b":-2.5,"c":"x","d":true,"e":null,"f":[18446744073709551615],"g":{"h":{}}}`), &m))
+
+	require.Equal(EventDataMap{
+		"a": EventDataInt64(1),
+		"b": EventDataFloat64(-2.5),
+		"c": EventDataString("x"),
+		"d": EventDataBool(true),
+		"e": EventDataMap(nil),
+		"f": EventDataSlice{EventDataUInt64(18446744073709551615)},
+		"g": EventDataMap{"h": EventDataMap{}},
+	}, m)
+
+	require.NotNil(json.Unmarshal([]byte(`[1]`), &m))
+
+}
@@ -0,0 +1,247 @@
+package logberry
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy determines what happens to an event generated while
+// the buffer between its generation and output is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the generating goroutine until there is
+	// room in the buffer.  No events are dropped.  This is the default.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the newly generated event.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest buffered event to make
+	// room for the newly generated one.  Without a buffer, i.e., of
+	// size zero, it behaves as OverflowDropNewest.
+	OverflowDropOldest
+
+	// OverflowTimeout blocks the generating goroutine until there is
+	// room in the buffer or a timeout elapses, in which case the new
+	// event is discarded.
+	OverflowTimeout
+)
+
+var overflownames = []string{
+	OverflowBlock:      "block",
+	OverflowDropNewest: "drop-newest",
+	OverflowDropOldest: "drop-oldest",
+	OverflowTimeout:    "timeout",
+}
+
+// String returns a short name for the OverflowPolicy.
+func (x OverflowPolicy) String() string {
+	if x < OverflowBlock || int(x) >= len(overflownames) {
+		return "unknown"
+	}
+	return overflownames[x]
+}
+
+type overflow struct {
+	policy  OverflowPolicy
+	timeout time.Duration
+}
+
+// eventqueue is a bounded buffer of events applying an OverflowPolicy
+// when full, counting the events it has discarded.  The consumer of
+// the queue marks each event it takes as processed once handled so
+// that the queue may be flushed.  Once stopped the queue accepts no
+// more events, and producers blocked on a full buffer are released,
+// but the events channel is never closed so that producers need not
+// be excluded from sending.  The consumer instead drains the queue
+// once stopping is closed.
+type eventqueue struct {
+	events chan *Event
+
+	stopping chan struct{}
+	stopped  int32
+	stoponce sync.Once
+	pushers  int32
+
+	queued    uint64
+	processed progress
+
+	overflow atomic.Value // overflow
+
+	dropped    uint64
+	unreported uint64
+}
+
+func neweventqueue(buffer int) *eventqueue {
+
+	q := &eventqueue{
+		events:   make(chan *Event, buffer),
+		stopping: make(chan struct{}),
+	}
+
+	q.overflow.Store(overflow{policy: OverflowBlock})
+
+	return q
+
+}
+
+func (q *eventqueue) setoverflow(policy OverflowPolicy, timeout time.Duration) {
+	q.overflow.Store(overflow{policy: policy, timeout: timeout})
+}
+
+// push adds the event to the queue according to the OverflowPolicy,
+// returning false if the queue has been stopped, in which case the
+// event is neither queued nor counted as dropped.  Events discarded by
+// the OverflowPolicy are counted as dropped.
+func (q *eventqueue) push(e *Event) bool {
+
+	// Producers are counted before checking for stop so that the
+	// consumer, having stopped the queue, may wait for those that did
+	// not see it to finish sending.
+	atomic.AddInt32(&q.pushers, 1)
+	defer atomic.AddInt32(&q.pushers, -1)
+
+	if atomic.LoadInt32(&q.stopped) != 0 {
+		return false
+	}
+
+	o := q.overflow.Load().(overflow)
+
+	if o.policy == OverflowDropOldest && cap(q.events) == 0 {
+		o.policy = OverflowDropNewest
+	}
+
+	switch o.policy {
+
+	case OverflowDropNewest:
+		select {
+		case q.events <- e:
+			q.enqueued()
+		default:
+			q.drop()
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.events <- e:
+				q.enqueued()
+				return true
+			default:
+			}
+
+			select {
+			case <-q.events:
+				q.drop()
+				q.processed.advance(1)
+			default:
+			}
+		}
+
+	case OverflowTimeout:
+		select {
+		case q.events <- e:
+			q.enqueued()
+			return true
+		default:
+		}
+
+		timer := time.NewTimer(o.timeout)
+		defer timer.Stop()
+
+		select {
+		case q.events <- e:
+			q.enqueued()
+		case <-timer.C:
+			q.drop()
+		case <-q.stopping:
+			return false
+		}
+
+	default:
+		select {
+		case q.events <- e:
+			q.enqueued()
+		case <-q.stopping:
+			return false
+		}
+
+	}
+
+	return true
+
+}
+
+// stop closes the queue to further events, releasing any producers
+// blocked on a full buffer.  It may be called more than once.
+func (q *eventqueue) stop() {
+	q.stoponce.Do(func() {
+		atomic.StoreInt32(&q.stopped, 1)
+		close(q.stopping)
+	})
+}
+
+// drain passes each event remaining in a stopped queue to the given
+// function, marking each processed, until the buffer is empty and no
+// producer may still send.
+func (q *eventqueue) drain(handle func(*Event)) {
+
+	for {
+		select {
+		case e := <-q.events:
+			handle(e)
+			q.done()
+
+		default:
+			if atomic.LoadInt32(&q.pushers) == 0 && len(q.events) == 0 {
+				return
+			}
+			runtime.Gosched()
+		}
+	}
+
+}
+
+func (q *eventqueue) enqueued() {
+	atomic.AddUint64(&q.queued, 1)
+}
+
+// done marks an event taken from the queue as processed.
+func (q *eventqueue) done() {
+	q.processed.advance(1)
+}
+
+// pending returns the number of events queued but not yet processed.
+func (q *eventqueue) pending() uint64 {
+
+	// Processing may be counted before the corresponding enqueue
+	processed := q.processed.count()
+	queued := atomic.LoadUint64(&q.queued)
+	if processed > queued {
+		return 0
+	}
+
+	return queued - processed
+
+}
+
+// flush waits until every event queued before the call has been
+// processed, or the context is done.
+func (q *eventqueue) flush(ctx context.Context) error {
+	return q.processed.wait(ctx, atomic.LoadUint64(&q.queued))
+}
+
+func (q *eventqueue) drop() {
+	atomic.AddUint64(&q.dropped, 1)
+	atomic.AddUint64(&q.unreported, 1)
+}
+
+// drops returns the number of events discarded since it was last
+// called and the total number discarded by the queue.
+func (q *eventqueue) drops() (uint64, uint64) {
+	return atomic.SwapUint64(&q.unreported, 0), atomic.LoadUint64(&q.dropped)
+}
@@ -0,0 +1,124 @@
+package logberry
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ID identifies a Task.  Its form depends on the IDGenerator of the
+// Task's Root.
+type ID string
+
+// RunID randomly identifies the current execution of the program.  It
+// is reported with every event so that tasks from different processes,
+// or restarted instances of the same program, may be distinguished in
+// merged logs even if their Task IDs collide.
+var RunID = NewRandomID()
+
+// An IDGenerator creates identifiers for Tasks.  It must be thread
+// safe.  See Root.SetIDGenerator.
+type IDGenerator interface {
+	NewID() ID
+}
+
+// CounterIDGenerator generates sequential decimal identifiers starting
+// from zero.  They are short and readable, but only unique within the
+// generator.  By default all Roots share a single CounterIDGenerator.
+type CounterIDGenerator struct {
+	next uint64
+}
+
+// NewID returns the next identifier in sequence.
+func (x *CounterIDGenerator) NewID() ID {
+	return ID(strconv.FormatUint(atomic.AddUint64(&x.next, 1)-1, 10))
+}
+
+// RandomIDGenerator generates random 128-bit identifiers, encoded as
+// 32 hexadecimal digits.  They are globally unique for all practical
+// purposes.
+type RandomIDGenerator struct{}
+
+// NewID returns a new random identifier.
+func (x RandomIDGenerator) NewID() ID {
+	return NewRandomID()
+}
+
+// NewRandomID returns a random 128-bit identifier, encoded as 32
+// hexadecimal digits.
+func NewRandomID() ID {
+	var b [16]byte
+	rand.Read(b[:])
+	return ID(hex.EncodeToString(b[:]))
+}
+
+// ULIDGenerator generates Universally Unique Lexicographically
+// Sortable Identifiers, 128-bit identifiers composed of a millisecond
+// timestamp and 80 random bits, encoded as 26 Crockford base32
+// characters.  Identifiers from a single generator sort in the order
+// they were generated, even within the same millisecond.
+type ULIDGenerator struct {
+	lock    sync.Mutex
+	last    uint64
+	entropy [10]byte
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewID returns a new ULID.
+func (x *ULIDGenerator) NewID() ID {
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+
+	x.lock.Lock()
+
+	if ms > x.last {
+		x.last = ms
+		rand.Read(x.entropy[:])
+	} else {
+		// Within the same millisecond, or if the clock has gone
+		// backwards, increment the previous entropy to stay monotonic.
+		ms = x.last
+		for i := len(x.entropy) - 1; i >= 0; i-- {
+			x.entropy[i]++
+			if x.entropy[i] != 0 {
+				break
+			}
+		}
+	}
+
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], ms<<16)
+	for i, v := range x.entropy {
+		b[6+i] = v
+	}
+
+	x.lock.Unlock()
+
+	return ID(encodeulid(b))
+
+}
+
+// encodeulid encodes the 128 bits as 26 base32 characters, the first
+// holding only the top 3 bits.
+func encodeulid(b [16]byte) string {
+
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out[:])
+
+}
+
+var defaultids = &CounterIDGenerator{}
@@ -0,0 +1,44 @@
+package logberry
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_ID_ULID(t *testing.T) {
+	require := require.New(t)
+
+	g := &ULIDGenerator{}
+
+	prev := g.NewID()
+	require.Len(string(prev), 26)
+
+	for i := 0; i < 1000; i++ {
+		id := g.NewID()
+		require.True(id > prev, "%v <= %v", id, prev)
+		prev = id
+	}
+
+}
+
+func Test_ID_Generator(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+	root.SetIDGenerator(RandomIDGenerator{})
+
+	parent := root.Task("Parent")
+	child := parent.Task("Child")
+
+	require.Len(string(parent.ID()), 32)
+	require.Equal(parent.ID(), capture.events[1].ParentID)
+	require.Equal(child.ID(), capture.events[1].TaskID)
+	require.Equal(RunID, capture.events[1].RunID)
+	require.Equal(ID(""), capture.events[0].ParentID)
+
+	root.Stop()
+
+}
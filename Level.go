@@ -0,0 +1,145 @@
+package logberry
+
+import (
+	"strings"
+)
+
+// Level is the severity of an Event.  Roots may be configured to drop
+// events below a minimum Level, either globally or per component.
+type Level int
+
+// These are the severities of events, in increasing order.
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+var levelnames = []string{
+	LevelTrace:   TRACE,
+	LevelDebug:   DEBUG,
+	LevelInfo:    INFO,
+	LevelWarning: WARNING,
+	LevelError:   ERROR,
+}
+
+// String returns the lowercase name of the Level, e.g., "warning".
+func (x Level) String() string {
+	if x < LevelTrace || int(x) >= len(levelnames) {
+		return "unknown"
+	}
+	return levelnames[x]
+}
+
+// MarshalText encodes the Level as its name, e.g., for JSON output.
+func (x Level) MarshalText() ([]byte, error) {
+	return []byte(x.String()), nil
+}
+
+// UnmarshalText decodes a Level from its name.
+func (x *Level) UnmarshalText(text []byte) error {
+	l, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*x = l
+	return nil
+}
+
+// ParseLevel returns the Level named by the given string.  Matching
+// is case insensitive.
+func ParseLevel(name string) (Level, error) {
+
+	n := strings.ToLower(strings.TrimSpace(name))
+	for l, s := range levelnames {
+		if s == n {
+			return Level(l), nil
+		}
+	}
+
+	return LevelInfo, NewError("Unknown level", D{"Level": name})
+
+}
+
+// EventLevel returns the Level of the given class of event.  Errors
+// and warnings map to their respective levels, debug and trace events
+// to theirs, and all other classes, including application specific
+// ones, are informational.
+func EventLevel(event string) Level {
+
+	switch event {
+	case TRACE:
+		return LevelTrace
+	case DEBUG:
+		return LevelDebug
+	case WARNING:
+		return LevelWarning
+	case ERROR:
+		return LevelError
+	default:
+		return LevelInfo
+	}
+
+}
+
+// levelpolicy captures the minimum Level of events to be output,
+// by default and for specific components.  It is not modified once
+// constructed so that it may be read without locking.
+type levelpolicy struct {
+	minimum    Level
+	components map[string]Level
+}
+
+func (x *levelpolicy) enabled(component string, level Level) bool {
+
+	if min, ok := x.components[component]; ok {
+		return level >= min
+	}
+
+	return level >= x.minimum
+
+}
+
+// parselevels reads a policy specification of the form
+// "db=debug,http=warning,*=info".  An entry without a component, or
+// with component "*", sets the default minimum.
+func parselevels(spec string) (*levelpolicy, error) {
+
+	p := &levelpolicy{
+		minimum:    LevelTrace,
+		components: make(map[string]Level),
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		component := "*"
+		name := entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			component = strings.TrimSpace(entry[:i])
+			name = entry[i+1:]
+		}
+
+		l, err := ParseLevel(name)
+		if err != nil {
+			return nil, WrapError("Invalid level specification", err,
+				D{"Entry": entry})
+		}
+
+		if component == "*" {
+			p.minimum = l
+		} else {
+			p.components[component] = l
+		}
+
+	}
+
+	return p, nil
+
+}
@@ -0,0 +1,59 @@
+package logberry
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_Level_Parse(t *testing.T) {
+	require := require.New(t)
+
+	l, err := ParseLevel("Warning")
+	require.Nil(err)
+	require.Equal(LevelWarning, l)
+
+	_, err = ParseLevel("loud")
+	require.NotNil(err)
+
+	err = NewRoot(1).SetLevels("db=debug,http=loud")
+	require.NotNil(err)
+
+}
+
+func Test_Level_Filter(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+	root := NewRoot(8)
+	root.AddOutputDriver(capture)
+
+	require.Nil(root.SetLevels("db=debug,http=warning,*=info"))
+
+	db := root.Component("db")
+	db.Trace("Dropped")
+	db.Debug("Kept")
+
+	http := root.Component("http")
+	http.Info("Dropped")
+	http.Warning("Kept")
+
+	task := root.Task("Other")
+	task.Debug("Dropped")
+	task.Event("metric", "Kept")
+
+	root.SetLevel("db", LevelTrace)
+	db.Trace("Kept")
+
+	root.Stop()
+
+	var messages []string
+	for _, e := range capture.events {
+		if e.Event != BEGIN {
+			messages = append(messages, e.Message)
+		}
+	}
+	require.Equal([]string{"Kept", "Kept", "Kept", "Kept"}, messages)
+
+	require.Equal(LevelDebug, capture.events[1].Level)
+
+}
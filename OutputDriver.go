@@ -1,5 +1,12 @@
 package logberry
 
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // An OutputDriver is registered to Roots and receives log events to
 // export, e.g., writing to disk, screen, or sending to a server.  To
 // do so, an OutputDriver is created and then passed to the
@@ -13,3 +20,189 @@ type OutputDriver interface {
 	Detach()
 	Event(event *Event)
 }
+
+// QueueOptions configures the dedicated queue of an OutputDriver
+// registered via Root.AddAsyncOutputDriver.
+type QueueOptions struct {
+	// Size is the number of events buffered for the driver.
+	Size int
+
+	// Overflow determines how events are handled when the buffer is
+	// full.  It defaults to OverflowBlock, which will in turn block the
+	// Root's delivery of events to all of its drivers.
+	Overflow OverflowPolicy
+
+	// Timeout is the time to wait for room in the buffer when using
+	// OverflowTimeout.
+	Timeout time.Duration
+}
+
+// An OutputHandle identifies an OutputDriver registered to a Root.  It
+// also serializes the driver's receipt of events and its detachment,
+// such that a driver never receives events once removed, and isolates
+// the Root from panics within the driver.
+type OutputHandle struct {
+	root   *Root
+	driver OutputDriver
+
+	lock        sync.Mutex
+	removed     bool
+	failures    int
+	quarantined bool
+
+	// Only used by asynchronous drivers
+	queue    *eventqueue
+	finished chan struct{}
+	runner   uint64 // goroutine delivering events
+}
+
+// Driver returns the OutputDriver registered under this handle.
+func (x *OutputHandle) Driver() OutputDriver {
+	return x.driver
+}
+
+// Remove detaches the driver from its Root.  It is identical to
+// calling RemoveOutputDriver on the Root.
+func (x *OutputHandle) Remove() {
+	x.root.RemoveOutputDriver(x)
+}
+
+// Quarantined reports whether the driver has been disabled after
+// repeatedly panicking, see Root.SetQuarantine.
+func (x *OutputHandle) Quarantined() bool {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	return x.quarantined
+}
+
+// dispatch passes the event to the driver, either directly or via
+// its queue if asynchronous.
+func (x *OutputHandle) dispatch(e *Event) {
+
+	if x.queue == nil {
+		x.event(e)
+		return
+	}
+
+	// Events arriving once the queue is closed are discarded
+	x.queue.push(e)
+
+}
+
+// run delivers events from the queue of an asynchronous driver.
+func (x *OutputHandle) run() {
+
+	atomic.StoreUint64(&x.runner, goid())
+
+	for {
+		select {
+		case e := <-x.queue.events:
+			x.event(e)
+			x.queue.done()
+
+		case <-x.queue.stopping:
+			x.queue.drain(x.event)
+			close(x.finished)
+			return
+		}
+	}
+
+}
+
+// close stops an asynchronous driver accepting events, returning once
+// its queue has been drained.  If called while delivering an event,
+// e.g., by an ErrorListener removing the driver, it returns at once
+// and the queue is drained after the delivery completes.
+func (x *OutputHandle) close() {
+
+	if x.queue == nil {
+		return
+	}
+
+	x.queue.stop()
+
+	if atomic.LoadUint64(&x.runner) == goid() {
+		return
+	}
+
+	<-x.finished
+
+}
+
+// pending returns the number of events queued for an asynchronous
+// driver but not yet delivered.
+func (x *OutputHandle) pending() uint64 {
+	if x.queue == nil {
+		return 0
+	}
+	return x.queue.pending()
+}
+
+func (x *OutputHandle) event(e *Event) {
+
+	// Errors are reported once unlocked so that listeners may safely
+	// manipulate the Root's drivers.
+	for _, err := range x.deliver(e) {
+		x.root.InternalError(err)
+	}
+
+}
+
+func (x *OutputHandle) deliver(e *Event) []error {
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	if x.removed || x.quarantined {
+		return nil
+	}
+
+	err := x.invoke(e)
+	if err == nil {
+		x.failures = 0
+		return nil
+	}
+
+	errs := []error{err}
+
+	x.failures++
+	if limit := x.root.quarantinelimit(); limit > 0 && x.failures >= limit {
+		x.quarantined = true
+		errs = append(errs, NewError("Output driver quarantined",
+			D{"Driver": fmt.Sprintf("%T", x.driver), "Failures": x.failures}))
+	}
+
+	return errs
+
+}
+
+// invoke passes the event to the driver, recovering any panic.
+func (x *OutputHandle) invoke(e *Event) (err *Error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			cause, ok := r.(error)
+			if !ok {
+				cause = fmt.Errorf("%v", r)
+			}
+			err = WrapError("Output driver panicked", cause,
+				D{"Driver": fmt.Sprintf("%T", x.driver), "Event": e})
+		}
+	}()
+
+	x.driver.Event(e)
+	return nil
+
+}
+
+func (x *OutputHandle) detach() {
+	x.close()
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	if !x.removed {
+		x.removed = true
+		x.driver.Detach()
+	}
+}
@@ -0,0 +1,65 @@
+package logberry
+
+import (
+	"fmt"
+)
+
+// A Processor is registered to Roots and given each event before it is
+// forwarded to the output drivers.  It may modify the event, e.g., to
+// add data or redact secrets, replace it by returning a different
+// event, or drop it by returning false.  Processors are invoked in
+// the order they were added, each given the event returned by the
+// previous.  They are invoked serially, in the same goroutine as
+// synchronous output drivers, and need not be thread safe unless
+// registered to more than one Root.
+type Processor interface {
+	Process(event *Event) (*Event, bool)
+}
+
+// ProcessorFunc adapts an ordinary function to the Processor
+// interface.
+type ProcessorFunc func(event *Event) (*Event, bool)
+
+// Process calls f(event).
+func (f ProcessorFunc) Process(event *Event) (*Event, bool) {
+	return f(event)
+}
+
+// process runs the event through the Root's processors, returning
+// false if it has been dropped.  A panicking processor is reported
+// to the ErrorListeners and the event passed on unchanged.
+func (x *Root) process(e *Event) (*Event, bool) {
+
+	for _, p := range x.processors.Load().([]Processor) {
+		e = x.invokeprocessor(p, e)
+		if e == nil {
+			return nil, false
+		}
+	}
+
+	return e, true
+
+}
+
+func (x *Root) invokeprocessor(p Processor, e *Event) (result *Event) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			cause, ok := r.(error)
+			if !ok {
+				cause = fmt.Errorf("%v", r)
+			}
+			x.InternalError(WrapError("Processor panicked", cause,
+				D{"Processor": fmt.Sprintf("%T", p), "Event": e}))
+			result = e
+		}
+	}()
+
+	next, ok := p.Process(e)
+	if !ok {
+		return nil
+	}
+
+	return next
+
+}
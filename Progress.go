@@ -0,0 +1,95 @@
+package logberry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// progress is a monotonically increasing count of completed work that
+// may be waited upon to reach a target.
+type progress struct {
+	done     uint64
+	nwaiters int32
+
+	lock    sync.Mutex
+	waiters []progresswaiter
+}
+
+type progresswaiter struct {
+	target uint64
+	ch     chan struct{}
+}
+
+func (x *progress) count() uint64 {
+	return atomic.LoadUint64(&x.done)
+}
+
+// advance records n more units of work as complete, releasing any
+// waiters whose target has been reached.
+func (x *progress) advance(n uint64) {
+
+	done := atomic.AddUint64(&x.done, n)
+
+	if atomic.LoadInt32(&x.nwaiters) == 0 {
+		return
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	remaining := x.waiters[:0]
+	for _, w := range x.waiters {
+		if w.target <= done {
+			close(w.ch)
+		} else {
+			remaining = append(remaining, w)
+		}
+	}
+	x.waiters = remaining
+	atomic.StoreInt32(&x.nwaiters, int32(len(remaining)))
+
+}
+
+// wait blocks until the count reaches the target or the context is
+// done, returning the context's error in the latter case.
+func (x *progress) wait(ctx context.Context, target uint64) error {
+
+	if x.count() >= target {
+		return nil
+	}
+
+	w := progresswaiter{target: target, ch: make(chan struct{})}
+
+	x.lock.Lock()
+	x.waiters = append(x.waiters, w)
+	atomic.StoreInt32(&x.nwaiters, int32(len(x.waiters)))
+	x.lock.Unlock()
+
+	// The count may have advanced before the waiter was registered
+	x.advance(0)
+
+	select {
+	case <-w.ch:
+		return nil
+
+	case <-ctx.Done():
+		x.lock.Lock()
+		for i, v := range x.waiters {
+			if v.ch == w.ch {
+				x.waiters = append(x.waiters[:i], x.waiters[i+1:]...)
+				break
+			}
+		}
+		atomic.StoreInt32(&x.nwaiters, int32(len(x.waiters)))
+		x.lock.Unlock()
+
+		select {
+		case <-w.ch:
+			return nil
+		default:
+			return ctx.Err()
+		}
+	}
+
+}
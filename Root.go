@@ -1,7 +1,13 @@
 package logberry
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,28 +16,103 @@ import (
 // be useful for logging outputs that may take some time, e.g.,
 // pushing to a logging server.  At the conclusion of the program Stop
 // should be called on the root to ensure that all of its events are
-// flushed before terminating.
+// flushed before terminating.  Events generated after the Root has
+// been stopped are written to its fallback driver, if any, or
+// otherwise dropped and reported to its ErrorListeners.
+//
+// A Root created by NewSyncRoot instead pushes events to its
+// OutputDrivers on the generating goroutine, serialized by a mutex.
 type Root struct {
-	outputdrivers  []OutputDriver
-	errorlisteners []ErrorListener
-	events         chan *Event
-	wg             sync.WaitGroup
+	outputdrivers  atomic.Value // []*OutputHandle
+	errorlisteners atomic.Value // []*ListenerHandle
+	processors     atomic.Value // []Processor
+	managemu       sync.Mutex
+	queue          *eventqueue
+	done           chan struct{}
+
+	ids atomic.Value // idgenerator
+
+	static   atomic.Value // EventDataMap
+	staticmu sync.Mutex
+
+	levels   atomic.Value // *levelpolicy
+	levelsmu sync.Mutex
+
+	dropinterval int64 // time.Duration
+	reconfigure  chan struct{}
+
+	stopped int32
+
+	fallback   OutputDriver
+	fallbackmu sync.Mutex
+
+	quarantine int32
+
+	stacktraces int32
+
+	synchronous bool
+	synclock    sync.Mutex
+	synccond    *sync.Cond
+	syncqueue   []*Event
+	dispatching bool
+	dispatcher  uint64
+	finished    bool
+	lastreport  time.Time
 }
 
+// DefaultDropReportInterval is the period at which a Root reports
+// events dropped due to its OverflowPolicy, unless otherwise set.
+const DefaultDropReportInterval = 10 * time.Second
+
 // NewRoot creates a new Root.  The buffer parameter indicates the
 // size of the channel buffer connecting event generation to outputs.
 // The goroutine that creates the Root should defer a call to Stop()
 // to ensure that all events are pushed.
 func NewRoot(buffer int) *Root {
 
+	r := newroot(buffer)
+
+	go r.run()
+
+	return r
+}
+
+// NewSyncRoot creates a new Root that pushes each event to its
+// OutputDrivers before the call generating the event returns, on the
+// calling goroutine.  Events are serialized by a mutex, so output
+// drivers still receive them one at a time and in order.  This may be
+// useful for tests and short lived tools, in which output must be
+// complete and ordered at the call site.  Asynchronous drivers may
+// still be added, in which case Stop or Flush should be called to
+// ensure their events are output.  Output drivers and ErrorListeners
+// may themselves log to the Root, in which case their events are
+// output once the event being dispatched has been, by the goroutine
+// dispatching it.
+func NewSyncRoot() *Root {
+
+	r := newroot(0)
+	r.synchronous = true
+	r.synccond = sync.NewCond(&r.synclock)
+	r.lastreport = time.Now()
+
+	return r
+}
+
+func newroot(buffer int) *Root {
+
 	r := &Root{
-		outputdrivers:  make([]OutputDriver, 0, 1),
-		errorlisteners: make([]ErrorListener, 0),
-		events:         make(chan *Event, buffer),
+		queue:        neweventqueue(buffer),
+		done:         make(chan struct{}),
+		dropinterval: int64(DefaultDropReportInterval),
+		reconfigure:  make(chan struct{}, 1),
 	}
 
-	r.wg.Add(1)
-	go r.run()
+	r.outputdrivers.Store([]*OutputHandle{})
+	r.errorlisteners.Store([]*ListenerHandle{})
+	r.processors.Store([]Processor{})
+	r.static.Store(EventDataMap(nil))
+	r.ids.Store(idgenerator{defaultids})
+	r.levels.Store(&levelpolicy{minimum: LevelTrace})
 
 	return r
 }
@@ -39,101 +120,631 @@ func NewRoot(buffer int) *Root {
 // Stop shuts down the Root.  Its internal channel is closed, and
 // newly generated log events no longer forwarded to output drivers.
 // Any previously buffered events are processed before Stop exits.
+// Stop may be called more than once and from multiple goroutines;
+// every call returns once the buffered events have been processed.
 func (x *Root) Stop() {
-	close(x.events)
-	x.wg.Wait()
+	x.StopContext(context.Background())
+}
+
+// StopContext shuts down the Root as Stop, but returns once the given
+// context is done even if buffered events have not all been
+// processed, e.g., because an output driver is blocked.  In that case
+// the number of events abandoned is returned along with the context's
+// error, and also reported to the ErrorListeners.  The abandoned
+// events may still be output later if the blockage clears before the
+// program exits.
+func (x *Root) StopContext(ctx context.Context) (uint64, error) {
+
+	if atomic.CompareAndSwapInt32(&x.stopped, 0, 1) {
+		if x.synchronous {
+			go x.finish()
+		} else {
+			x.queue.stop()
+		}
+	}
+
+	select {
+	case <-x.done:
+		return 0, nil
+
+	case <-ctx.Done():
+		abandoned := x.queue.pending()
+		for _, h := range x.outputdrivers.Load().([]*OutputHandle) {
+			abandoned += h.pending()
+		}
+		x.InternalError(WrapError("Abandoned log events at stop", ctx.Err(),
+			D{"Abandoned": abandoned}))
+		return abandoned, ctx.Err()
+	}
+
+}
+
+// Flush waits until every event generated before the call has been
+// processed by the output drivers, or the given context is done, in
+// which case the context's error is returned.  Events generated
+// concurrently with the call may or may not be waited upon.  Flush may
+// be called from multiple goroutines, and at any time including after
+// the Root has been stopped.
+func (x *Root) Flush(ctx context.Context) error {
+
+	// Events queued by an output driver or ErrorListener of a
+	// synchronous Root are dispatched once it returns
+	if !x.syncreentrant() {
+		err := x.queue.flush(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, h := range x.outputdrivers.Load().([]*OutputHandle) {
+		if h.queue != nil {
+			err := h.queue.flush(ctx)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+
+}
+
+// Stopped reports whether Stop has been called on the Root.
+func (x *Root) Stopped() bool {
+	return atomic.LoadInt32(&x.stopped) != 0
+}
+
+// SetQuarantine sets the number of consecutive events on which an
+// output driver may panic before it is quarantined, i.e., no longer
+// sent events.  Each panic is recovered and reported to the
+// ErrorListeners, along with the driver's type and the offending
+// event, as is the quarantine.  A limit of zero, the default, never
+// quarantines drivers.
+func (x *Root) SetQuarantine(failures int) {
+	atomic.StoreInt32(&x.quarantine, int32(failures))
+}
+
+func (x *Root) quarantinelimit() int {
+	return int(atomic.LoadInt32(&x.quarantine))
+}
+
+// SetStackTraces enables or disables capturing a Stack on the Errors
+// generated by Task.Failure, Task.Error, and Task.WrapError for Tasks
+// under this Root, even if stack traces are not enabled for all Roots
+// via the SetStackTraces function.
+func (x *Root) SetStackTraces(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&x.stacktraces, v)
+}
+
+// StackTraces reports whether Errors generated by Tasks under this
+// Root capture their call stacks, either because enabled on this Root
+// or for all Roots.
+func (x *Root) StackTraces() bool {
+	return atomic.LoadInt32(&x.stacktraces) != 0 || StackTraces()
+}
+
+// SetFallbackDriver sets an OutputDriver to which events generated
+// after the Root has been stopped are written, synchronously on the
+// generating goroutine.  Without a fallback driver such events are
+// dropped and reported to the ErrorListeners.  Passing nil removes
+// the fallback driver.
+func (x *Root) SetFallbackDriver(driver OutputDriver) {
+
+	x.fallbackmu.Lock()
+	defer x.fallbackmu.Unlock()
+
+	if x.fallback != nil {
+		x.fallback.Detach()
+	}
+
+	if driver != nil {
+		driver.Attach(x)
+	}
+	x.fallback = driver
+
+}
+
+// SetOverflowPolicy determines how events are handled when generated
+// while the Root's buffer is full, e.g., because an output driver is
+// slow.  The timeout parameter is only used by OverflowTimeout.  By
+// default a Root uses OverflowBlock.  Dropped events are counted and
+// periodically reported, see SetDropReportInterval.  The policy may be
+// changed at any time.
+func (x *Root) SetOverflowPolicy(policy OverflowPolicy, timeout time.Duration) {
+	x.queue.setoverflow(policy, timeout)
+}
+
+// SetDropReportInterval sets the period at which the number of events
+// dropped due to the OverflowPolicy is reported, both to the
+// ErrorListeners and as a warning event to the output drivers.
+// Nothing is reported for periods in which no events were dropped.
+// A non-positive interval restores DefaultDropReportInterval.
+func (x *Root) SetDropReportInterval(interval time.Duration) {
+
+	if interval <= 0 {
+		interval = DefaultDropReportInterval
+	}
+
+	atomic.StoreInt64(&x.dropinterval, int64(interval))
+
+	select {
+	case x.reconfigure <- struct{}{}:
+	default:
+	}
+
+}
+
+// Dropped returns the total number of events this Root has discarded
+// due to its OverflowPolicy.
+func (x *Root) Dropped() uint64 {
+	_, total := x.queue.drops()
+	return total
 }
 
 func (x *Root) run() {
 
+	ticker := time.NewTicker(time.Duration(atomic.LoadInt64(&x.dropinterval)))
+
 	for {
 
-		e, more := <-x.events
-		if !more {
-			break
+		select {
+		case e := <-x.queue.events:
+			x.dispatch(e)
+			x.queue.done()
+
+		case <-x.queue.stopping:
+			ticker.Stop()
+			x.queue.drain(x.dispatch)
+			x.finish()
+			return
+
+		case <-ticker.C:
+			x.reportdrops()
+
+		case <-x.reconfigure:
+			ticker.Stop()
+			ticker = time.NewTicker(time.Duration(atomic.LoadInt64(&x.dropinterval)))
 		}
 
-		for _, driver := range x.outputdrivers {
-			driver.Event(e)
+	}
+
+}
+
+// finish completes the shutdown of the Root once all of its buffered
+// events have been dispatched.
+func (x *Root) finish() {
+
+	// Synchronous events are no longer dispatched once any in progress
+	// have completed.
+	if x.synchronous {
+		x.synclock.Lock()
+		for x.dispatching {
+			x.synccond.Wait()
 		}
+		x.finished = true
+		x.synccond.Broadcast()
+		x.synclock.Unlock()
+	}
+
+	x.reportdrops()
+
+	for _, h := range x.outputdrivers.Load().([]*OutputHandle) {
+		h.close()
+	}
 
+	close(x.done)
+
+}
+
+// dispatch runs an event through the processors and then forwards it
+// to each of the output drivers.
+func (x *Root) dispatch(e *Event) {
+
+	e, ok := x.process(e)
+	if !ok {
+		return
 	}
 
-	x.wg.Done()
+	for _, h := range x.outputdrivers.Load().([]*OutputHandle) {
+		h.dispatch(e)
+	}
+}
+
+// reportdrops notifies the ErrorListeners and output drivers of any
+// events dropped since the last report, by the Root or by the queues
+// of asynchronous drivers.
+func (x *Root) reportdrops() {
+
+	x.reportqueuedrops(x.queue, D{})
+
+	for _, h := range x.outputdrivers.Load().([]*OutputHandle) {
+		if h.queue != nil {
+			x.reportqueuedrops(h.queue, D{"Driver": fmt.Sprintf("%T", h.driver)})
+		}
+	}
+
+}
+
+func (x *Root) reportqueuedrops(queue *eventqueue, data D) {
+
+	n, total := queue.drops()
+	if n == 0 {
+		return
+	}
+
+	data["Dropped"] = n
+	data["Total"] = total
+
+	x.InternalError(NewError("Dropped log events", data))
+
+	x.dispatch(&Event{
+		RunID:     RunID,
+		Component: "logberry",
+		Event:     WARNING,
+		Level:     LevelWarning,
+		Message:   "Dropped log events",
+		Data:      Aggregate([]interface{}{data}),
+		Static:    x.Static(),
+		Timestamp: time.Now(),
+	})
 
 }
 
 // ClearOutputDrivers removes all of the currently registered outputs.
-// This is not thread safe with event generation, drivers are assumed
-// to be managed in serial at startup.
+// It may be called at any time.  Once it returns, none of the removed
+// drivers will receive further events.
 func (x *Root) ClearOutputDrivers() {
-	for _, o := range x.outputdrivers {
-		o.Detach()
+
+	x.managemu.Lock()
+	prev := x.outputdrivers.Load().([]*OutputHandle)
+	x.outputdrivers.Store([]*OutputHandle{})
+	x.managemu.Unlock()
+
+	for _, h := range prev {
+		h.detach()
 	}
-	x.outputdrivers = make([]OutputDriver, 0, 1)
+
 }
 
 // AddOutputDriver includes the given additional output in those to
-// which this Root forwards events.  This is not thread safe
-// with event generation, drivers are assumed to be attached in serial
-// at startup.
-func (x *Root) AddOutputDriver(driver OutputDriver) {
+// which this Root forwards events.  It may be called at any time,
+// including while events are being generated.  The returned handle
+// may be used to later remove the driver individually.
+func (x *Root) AddOutputDriver(driver OutputDriver) *OutputHandle {
+
+	h := &OutputHandle{
+		root:   x,
+		driver: driver,
+	}
+
+	x.register(h)
+
+	return h
+
+}
+
+// AddAsyncOutputDriver includes the given additional output in those
+// to which this Root forwards events, as AddOutputDriver, but
+// delivers events to it from a dedicated goroutine via its own queue
+// configured by the given options.  A slow asynchronous driver
+// therefore does not delay the others, subject to the queue's
+// OverflowPolicy.  Events are still received by the driver in order.
+// Passing nil options creates an unbuffered, blocking queue.
+func (x *Root) AddAsyncOutputDriver(driver OutputDriver, options *QueueOptions) *OutputHandle {
+
+	if options == nil {
+		options = &QueueOptions{}
+	}
+
+	h := &OutputHandle{
+		root:     x,
+		driver:   driver,
+		queue:    neweventqueue(options.Size),
+		finished: make(chan struct{}),
+	}
+
+	h.queue.setoverflow(options.Overflow, options.Timeout)
+
+	go h.run()
+
+	x.register(h)
+
+	return h
+
+}
+
+func (x *Root) register(h *OutputHandle) {
 
 	// Must attach first so that the OutputDriver won't receive output
 	// until it knows its root.
-	driver.Attach(x)
-	x.outputdrivers = append(x.outputdrivers, driver)
+	h.driver.Attach(x)
+
+	x.managemu.Lock()
+	prev := x.outputdrivers.Load().([]*OutputHandle)
+	next := append(make([]*OutputHandle, 0, len(prev)+1), prev...)
+	x.outputdrivers.Store(append(next, h))
+	x.managemu.Unlock()
+
+}
+
+// RemoveOutputDriver removes the output registered under the given
+// handle, as returned by AddOutputDriver.  It may be called at any
+// time.  Once it returns, the driver will receive no further events.
+// Removing a driver more than once has no effect.
+func (x *Root) RemoveOutputDriver(h *OutputHandle) {
+
+	x.managemu.Lock()
+	prev := x.outputdrivers.Load().([]*OutputHandle)
+	next := make([]*OutputHandle, 0, len(prev))
+	for _, o := range prev {
+		if o != h {
+			next = append(next, o)
+		}
+	}
+	x.outputdrivers.Store(next)
+	x.managemu.Unlock()
+
+	h.detach()
 
 }
 
 // SetOutputDriver makes the given driver the only output for this
-// root.  It is identical to calling x.ClearOutputDrivers() and then
-// x.AddOutputDriver(driver).
-func (x *Root) SetOutputDriver(driver OutputDriver) {
-	x.ClearOutputDrivers()
-	x.AddOutputDriver(driver)
+// root.  It is equivalent to calling x.ClearOutputDrivers() and then
+// x.AddOutputDriver(driver), except that no events are missed between
+// the two.
+func (x *Root) SetOutputDriver(driver OutputDriver) *OutputHandle {
+
+	h := &OutputHandle{
+		root:   x,
+		driver: driver,
+	}
+
+	driver.Attach(x)
+
+	x.managemu.Lock()
+	prev := x.outputdrivers.Load().([]*OutputHandle)
+	x.outputdrivers.Store([]*OutputHandle{h})
+	x.managemu.Unlock()
+
+	for _, o := range prev {
+		o.detach()
+	}
+
+	return h
+
+}
+
+// AddProcessor appends the given Processor to the chain through which
+// events are run before being forwarded to the output drivers.  It
+// may be called at any time.
+func (x *Root) AddProcessor(p Processor) {
+
+	x.managemu.Lock()
+	prev := x.processors.Load().([]Processor)
+	next := append(make([]Processor, 0, len(prev)+1), prev...)
+	x.processors.Store(append(next, p))
+	x.managemu.Unlock()
+
 }
 
-// ClearErrorListeners removes all of the registered listeners.
+// ClearProcessors removes all of the registered processors.  It may be
+// called at any time.
+func (x *Root) ClearProcessors() {
+	x.managemu.Lock()
+	x.processors.Store([]Processor{})
+	x.managemu.Unlock()
+}
+
+// ClearErrorListeners removes all of the registered listeners.  It
+// may be called at any time.
 func (x *Root) ClearErrorListeners() {
-	x.errorlisteners = make([]ErrorListener, 0)
+	x.managemu.Lock()
+	x.errorlisteners.Store([]*ListenerHandle{})
+	x.managemu.Unlock()
 }
 
 // AddErrorListener includes the given listener among those to which
-// internal logging errors are reported.
-func (x *Root) AddErrorListener(listener ErrorListener) {
-	x.errorlisteners = append(x.errorlisteners, listener)
+// internal logging errors are reported.  It may be called at any
+// time.  The returned handle may be used to later remove the listener
+// individually.
+func (x *Root) AddErrorListener(listener ErrorListener) *ListenerHandle {
+
+	h := &ListenerHandle{
+		root:     x,
+		listener: listener,
+	}
+
+	x.managemu.Lock()
+	prev := x.errorlisteners.Load().([]*ListenerHandle)
+	next := append(make([]*ListenerHandle, 0, len(prev)+1), prev...)
+	x.errorlisteners.Store(append(next, h))
+	x.managemu.Unlock()
+
+	return h
+
+}
+
+// RemoveErrorListener removes the listener registered under the given
+// handle, as returned by AddErrorListener.  It may be called at any
+// time.
+func (x *Root) RemoveErrorListener(h *ListenerHandle) {
+
+	x.managemu.Lock()
+	prev := x.errorlisteners.Load().([]*ListenerHandle)
+	next := make([]*ListenerHandle, 0, len(prev))
+	for _, l := range prev {
+		if l != h {
+			next = append(next, l)
+		}
+	}
+	x.errorlisteners.Store(next)
+	x.managemu.Unlock()
+
 }
 
 // SetErrorListener makes the given listener the only one for this
 // Root.  It is identical to calling x.ClearErrorListeners()
 // and then x.AddErrorListener(listener).
-func (x *Root) SetErrorListener(listener ErrorListener) {
-	x.ClearErrorListeners()
-	x.AddErrorListener(listener)
+func (x *Root) SetErrorListener(listener ErrorListener) *ListenerHandle {
+
+	h := &ListenerHandle{
+		root:     x,
+		listener: listener,
+	}
+
+	x.managemu.Lock()
+	x.errorlisteners.Store([]*ListenerHandle{h})
+	x.managemu.Unlock()
+
+	return h
+
+}
+
+// idgenerator wraps an IDGenerator so that differing implementations
+// may be stored in an atomic.Value.
+type idgenerator struct {
+	IDGenerator
+}
+
+// SetIDGenerator sets the generator of identifiers for Tasks
+// subsequently created under this Root.  By default all Roots share a
+// CounterIDGenerator, producing short identifiers unique only within
+// the process.  A RandomIDGenerator or ULIDGenerator produces globally
+// unique identifiers, such that task hierarchies may be reconstructed
+// from merged logs of many programs.
+func (x *Root) SetIDGenerator(generator IDGenerator) {
+	x.ids.Store(idgenerator{generator})
+}
+
+func (x *Root) newid() ID {
+	return x.ids.Load().(idgenerator).NewID()
+}
+
+// SetStatic sets data to be attached to every event generated under
+// this Root, e.g., the program name, host, process ID, or deployment
+// labels, replacing any previously set.  The variadic data parameter
+// is aggregated as a D.  The data is attached to events as their
+// Static field rather than merged into their Data.  It may be called
+// at any time, affecting subsequently generated events.
+func (x *Root) SetStatic(data ...interface{}) {
+
+	d := Aggregate(data)
+	if len(d) == 0 {
+		d = nil
+	}
+
+	x.staticmu.Lock()
+	x.static.Store(d)
+	x.staticmu.Unlock()
+
+}
+
+// AddStatic incorporates the given data into that attached to every
+// event generated under this Root, as SetStatic, but retaining the
+// previously set data.
+func (x *Root) AddStatic(data ...interface{}) {
+
+	x.staticmu.Lock()
+	defer x.staticmu.Unlock()
+
+	d := EventDataMap{}
+	for k, v := range x.static.Load().(EventDataMap) {
+		d[k] = v
+	}
+	for _, v := range data {
+		d.Aggregate(v)
+	}
+
+	x.static.Store(d)
+
+}
+
+// Static returns the data attached to every event generated under
+// this Root.  It must not be modified.
+func (x *Root) Static() EventDataMap {
+	return x.static.Load().(EventDataMap)
+}
+
+// SetLevels configures the minimum Level of events forwarded to
+// output drivers, from a specification of comma separated
+// component=level entries, e.g., "db=debug,http=warning,*=info".  The
+// entry for "*", or an entry without a component, sets the minimum
+// for all components not otherwise listed, including top level Tasks
+// without a component.  Events below the minimum are dropped by the
+// generating Task before any of their data is aggregated.  The
+// policy replaces any previously configured and may be changed at
+// any time.
+func (x *Root) SetLevels(spec string) error {
+
+	p, err := parselevels(spec)
+	if err != nil {
+		return err
+	}
+
+	x.levelsmu.Lock()
+	x.levels.Store(p)
+	x.levelsmu.Unlock()
+
+	return nil
+
+}
+
+// SetLevel configures the minimum Level of events forwarded to output
+// drivers for the given component, or for all components not
+// otherwise configured if component is "*".  Other entries in the
+// current policy are retained.
+func (x *Root) SetLevel(component string, level Level) {
+
+	x.levelsmu.Lock()
+	defer x.levelsmu.Unlock()
+
+	prev := x.levels.Load().(*levelpolicy)
+
+	p := &levelpolicy{
+		minimum:    prev.minimum,
+		components: make(map[string]Level, len(prev.components)+1),
+	}
+	for c, l := range prev.components {
+		p.components[c] = l
+	}
+
+	if component == "*" {
+		p.minimum = level
+	} else {
+		p.components[component] = level
+	}
+
+	x.levels.Store(p)
+
+}
+
+// Enabled reports whether events of the given Level generated under
+// the given component are forwarded to output drivers.
+func (x *Root) Enabled(component string, level Level) bool {
+	return x.levels.Load().(*levelpolicy).enabled(component, level)
 }
 
 // Task creates a new top level Task under this Root,
 // representing a particular line of activity.
 func (x *Root) Task(activity string, data ...interface{}) *Task {
-	t := newtask(nil, "", activity, data)
-	t.root = x
-	return t
+	return newtask(x, nil, "", activity, data, nil)
 }
 
 // Component creates a new top level Task under this Root,
 // representing a grouping of related functionality.
 func (x *Root) Component(component string, data ...interface{}) *Task {
-	t := newtask(nil, component, "Component "+component, data)
-	t.root = x
-	return t
+	return newtask(x, nil, component, "Component "+component, data, nil)
 }
 
 // InternalError reports an internal logging error.  It is generally
 // to be used only by OutputDrivers.
 func (x *Root) InternalError(err error) {
-	for _, listener := range x.errorlisteners {
-		listener.Error(err)
+	for _, h := range x.errorlisteners.Load().([]*ListenerHandle) {
+		h.listener.Error(err)
 	}
 	// end logerror
 }
@@ -141,13 +752,24 @@ func (x *Root) InternalError(err error) {
 // event indicates something to report, a log entry to make.  It is
 // generally to be used by Tasks.
 func (x *Root) event(task *Task, event string, message string, data EventDataMap) *Event {
+	e := x.newevent(task, event, message, data)
+	x.push(e)
+	return e
+}
+
+// newevent constructs an Event generated by the given Task, without
+// pushing it to the output drivers.
+func (x *Root) newevent(task *Task, event string, message string, data EventDataMap) *Event {
 
 	e := &Event{
 		TaskID:    task.uid,
+		RunID:     RunID,
 		Component: task.component,
 		Event:     event,
+		Level:     EventLevel(event),
 		Message:   message,
 		Data:      data,
+		Static:    x.Static(),
 
 		Timestamp: time.Now(),
 	}
@@ -156,9 +778,188 @@ func (x *Root) event(task *Task, event string, message string, data EventDataMap
 		e.ParentID = task.parent.uid
 	}
 
-	x.events <- e
+	if task.trace.IsValid() {
+		e.TraceID = task.trace.TraceID.String()
+		e.SpanID = task.trace.SpanID.String()
+		if task.parentspan.IsValid() {
+			e.ParentSpanID = task.parentspan.String()
+		}
+	}
 
 	return e
 
-	// end event
+	// end newevent
+}
+
+// push forwards a constructed Event to the output drivers.  It never
+// blocks once the Root has been stopped, even if the output drivers
+// are.
+func (x *Root) push(e *Event) {
+
+	if x.Stopped() {
+		x.stoppedevent(e)
+		return
+	}
+
+	if x.synchronous {
+		x.syncdispatch(e)
+		return
+	}
+
+	if !x.queue.push(e) {
+		x.stoppedevent(e)
+	}
+
+}
+
+// syncdispatch forwards an event to the output drivers on the calling
+// goroutine, also reporting dropped events from asynchronous drivers
+// in lieu of the run goroutine's periodic reports.  Concurrent callers
+// wait for the event being dispatched to complete.  An event generated
+// by the dispatching goroutine itself, i.e., by an output driver or
+// ErrorListener logging to this same Root, is instead queued and
+// dispatched once its current event is complete, rather than
+// deadlocking.
+func (x *Root) syncdispatch(e *Event) {
+
+	id := goid()
+
+	x.synclock.Lock()
+
+	for x.dispatching && x.dispatcher != id && !x.finished {
+		x.synccond.Wait()
+	}
+
+	if x.finished {
+		x.synclock.Unlock()
+		x.stoppedevent(e)
+		return
+	}
+
+	if x.dispatching {
+		x.syncqueue = append(x.syncqueue, e)
+		x.queue.enqueued()
+		x.synclock.Unlock()
+		return
+	}
+
+	x.dispatching = true
+	x.dispatcher = id
+
+	report := false
+	interval := time.Duration(atomic.LoadInt64(&x.dropinterval))
+	if time.Since(x.lastreport) >= interval {
+		x.lastreport = time.Now()
+		report = true
+	}
+
+	x.synclock.Unlock()
+
+	x.dispatch(e)
+
+	x.synclock.Lock()
+
+	for report || len(x.syncqueue) > 0 {
+		events := x.syncqueue
+		x.syncqueue = nil
+		x.synclock.Unlock()
+
+		for _, e := range events {
+			x.dispatch(e)
+			x.queue.done()
+		}
+
+		if report {
+			report = false
+			x.reportdrops()
+		}
+
+		x.synclock.Lock()
+	}
+
+	x.dispatching = false
+	x.synccond.Broadcast()
+	x.synclock.Unlock()
+
+}
+
+// syncreentrant reports whether the calling goroutine is dispatching
+// an event of a synchronous Root, i.e., is an output driver or
+// ErrorListener of the Root.
+func (x *Root) syncreentrant() bool {
+
+	if !x.synchronous {
+		return false
+	}
+
+	x.synclock.Lock()
+	defer x.synclock.Unlock()
+
+	return x.dispatching && x.dispatcher == goid()
+
+}
+
+// goid returns the identifier of the calling goroutine, as given in
+// the header of its stack trace, "goroutine 7 [running]:".
+func goid() uint64 {
+
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+
+	s := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	if i := bytes.IndexByte(s, ' '); i >= 0 {
+		s = s[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(s), 10, 64)
+	return id
+
+}
+
+// stoppedevent handles an event generated after the Root has been
+// stopped, writing it to the fallback driver if there is one.  Events
+// are only written to the fallback once the buffered events have been
+// output, so that it receives events in order and never concurrently.
+// Those generated before then, including if the drivers are blocked
+// and the Root never finishes stopping, are dropped rather than
+// waiting.
+func (x *Root) stoppedevent(e *Event) {
+
+	x.fallbackmu.Lock()
+	fallback := x.fallback
+	x.fallbackmu.Unlock()
+
+	if fallback != nil {
+		select {
+		case <-x.done:
+			x.fallbackevent(e)
+			return
+		default:
+		}
+	}
+
+	x.queue.drop()
+	_, total := x.queue.drops()
+
+	x.InternalError(NewError("Dropped log event generated after stop",
+		D{"Component": e.Component, "Event": e.Event, "Message": e.Message,
+			"Total": total}))
+
+}
+
+// fallbackevent writes an event to the fallback driver, if still set.
+func (x *Root) fallbackevent(e *Event) {
+
+	x.fallbackmu.Lock()
+	defer x.fallbackmu.Unlock()
+
+	if x.fallback == nil {
+		return
+	}
+
+	e, ok := x.process(e)
+	if ok {
+		x.fallback.Event(e)
+	}
+
 }
@@ -0,0 +1,715 @@
+package logberry
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+type blockingoutput struct {
+	captureoutput
+	gate chan struct{}
+}
+
+func (x *blockingoutput) Event(event *Event) {
+	<-x.gate
+	x.captureoutput.Event(event)
+}
+
+type captureerrors struct {
+	lock   sync.Mutex
+	errors []error
+}
+
+func (x *captureerrors) Error(err error) {
+	x.lock.Lock()
+	x.errors = append(x.errors, err)
+	x.lock.Unlock()
+}
+
+func Test_Root_DropNewest(t *testing.T) {
+	require := require.New(t)
+
+	output := &blockingoutput{gate: make(chan struct{})}
+	listener := &captureerrors{}
+
+	root := NewRoot(2)
+	root.AddOutputDriver(output)
+	root.AddErrorListener(listener)
+	root.SetOverflowPolicy(OverflowDropNewest, 0)
+
+	task := root.Task("Flood")
+	for i := 0; i < 10; i++ {
+		task.Info("Flooding")
+	}
+
+	close(output.gate)
+	root.Stop()
+
+	require.True(root.Dropped() > 0)
+	require.Equal(int(11-root.Dropped())+1, len(output.events))
+
+	last := output.events[len(output.events)-1]
+	require.Equal("Dropped log events", last.Message)
+	require.Equal(EventDataUInt64(root.Dropped()), last.Data["Dropped"])
+
+	require.Len(listener.errors, 1)
+
+}
+
+func Test_Root_DropOldest(t *testing.T) {
+	require := require.New(t)
+
+	output := &blockingoutput{gate: make(chan struct{})}
+
+	root := NewRoot(2)
+	root.AddOutputDriver(output)
+	root.SetOverflowPolicy(OverflowDropOldest, 0)
+
+	task := root.Task("Flood")
+	for i := 0; i < 10; i++ {
+		task.Info("Flooding", D{"I": i})
+	}
+
+	close(output.gate)
+	root.Stop()
+
+	require.True(root.Dropped() > 0)
+
+	// The most recent events survive
+	last := output.events[len(output.events)-2]
+	require.Equal(EventDataInt64(9), last.Data["I"])
+
+}
+
+func Test_Root_Timeout(t *testing.T) {
+	require := require.New(t)
+
+	output := &blockingoutput{gate: make(chan struct{})}
+
+	root := NewRoot(0)
+	root.AddOutputDriver(output)
+	root.SetOverflowPolicy(OverflowTimeout, time.Millisecond)
+
+	task := root.Task("Stall")
+	task.Info("Stalled")
+	task.Info("Stalled")
+
+	close(output.gate)
+	root.Stop()
+
+	require.True(root.Dropped() > 0)
+
+}
+
+func Test_Root_StopTwice(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+	listener := &captureerrors{}
+
+	root := NewRoot(4)
+	root.AddOutputDriver(capture)
+	root.AddErrorListener(listener)
+
+	task := root.Task("Straggler")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			root.Stop()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	require.True(root.Stopped())
+
+	task.Success()
+	require.Len(capture.events, 1)
+	require.Len(listener.errors, 1)
+	require.Equal(uint64(1), root.Dropped())
+
+	root.Stop()
+
+}
+
+func Test_Root_Fallback(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+	fallback := &captureoutput{}
+
+	root := NewRoot(4)
+	root.AddOutputDriver(capture)
+	root.SetFallbackDriver(fallback)
+
+	task := root.Task("Straggler")
+	root.Stop()
+	task.Success()
+
+	require.Len(capture.events, 1)
+	require.Len(fallback.events, 1)
+	require.Equal(SUCCESS, fallback.events[0].Event)
+
+}
+
+func Test_Root_Flush(t *testing.T) {
+	require := require.New(t)
+
+	output := &blockingoutput{gate: make(chan struct{})}
+
+	root := NewRoot(8)
+	root.AddOutputDriver(output)
+
+	task := root.Task("Flush")
+	task.Info("Queued")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	require.Equal(context.DeadlineExceeded, root.Flush(ctx))
+	cancel()
+
+	close(output.gate)
+	require.Nil(root.Flush(context.Background()))
+	require.Len(output.events, 2)
+
+	root.Stop()
+	require.Nil(root.Flush(context.Background()))
+
+}
+
+func Test_Root_StopContext(t *testing.T) {
+	require := require.New(t)
+
+	output := &blockingoutput{gate: make(chan struct{})}
+	listener := &captureerrors{}
+
+	root := NewRoot(8)
+	root.AddOutputDriver(output)
+	root.AddErrorListener(listener)
+
+	task := root.Task("Stuck")
+	task.Info("Queued")
+	task.Success()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	abandoned, err := root.StopContext(ctx)
+	cancel()
+
+	require.Equal(context.DeadlineExceeded, err)
+	require.Equal(uint64(3), abandoned)
+	require.Len(listener.errors, 1)
+
+	close(output.gate)
+	abandoned, err = root.StopContext(context.Background())
+	require.Nil(err)
+	require.Zero(abandoned)
+
+}
+
+func Test_Root_RemoveOutputDriver(t *testing.T) {
+	require := require.New(t)
+
+	first := &captureoutput{}
+	second := &captureoutput{}
+
+	root := NewRoot(8)
+	h := root.AddOutputDriver(first)
+	root.AddOutputDriver(second)
+
+	task := root.Task("Detach")
+	require.Nil(root.Flush(context.Background()))
+
+	h.Remove()
+	h.Remove()
+
+	task.Success()
+	root.Stop()
+
+	require.Len(first.events, 1)
+	require.Len(second.events, 2)
+
+}
+
+func Test_Root_ConcurrentDrivers(t *testing.T) {
+
+	root := NewRoot(8)
+	task := root.Task("Churn")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			for j := 0; j < 100; j++ {
+				task.Info("Churning")
+			}
+			wg.Done()
+		}()
+		go func() {
+			for j := 0; j < 100; j++ {
+				root.AddOutputDriver(&captureoutput{}).Remove()
+				root.AddErrorListener(&captureerrors{}).Remove()
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	root.Stop()
+
+}
+
+type panickingoutput struct {
+	captureoutput
+}
+
+func (x *panickingoutput) Event(event *Event) {
+	if event.Event == WARNING {
+		panic("Driver failure")
+	}
+	x.captureoutput.Event(event)
+}
+
+func Test_Root_DriverPanic(t *testing.T) {
+	require := require.New(t)
+
+	faulty := &panickingoutput{}
+	capture := &captureoutput{}
+	listener := &captureerrors{}
+
+	root := NewRoot(8)
+	h := root.AddOutputDriver(faulty)
+	root.AddOutputDriver(capture)
+	root.AddErrorListener(listener)
+	root.SetQuarantine(2)
+
+	task := root.Task("Panic")
+	task.Warning("Panics")
+	task.Info("Survives")
+	task.Warning("Panics")
+	task.Warning("Panics")
+	task.Info("Quarantined")
+	root.Stop()
+
+	require.True(h.Quarantined())
+	require.Len(capture.events, 6)
+	require.Len(faulty.events, 2)
+
+	// Two panics, then the second consecutive panic and quarantine
+	require.Len(listener.errors, 4)
+	require.Equal("Output driver panicked", listener.errors[0].(*Error).Message)
+	require.Equal("Output driver quarantined", listener.errors[3].(*Error).Message)
+
+}
+
+func Test_Root_QuarantineRemove(t *testing.T) {
+	require := require.New(t)
+
+	faulty := &panickingoutput{}
+
+	root := NewRoot(8)
+	h := root.AddAsyncOutputDriver(faulty, &QueueOptions{Size: 4})
+	root.SetQuarantine(1)
+
+	var once sync.Once
+	removed := make(chan struct{})
+	root.SetErrorListener(errorfunc(func(err error) {
+		if h.Quarantined() {
+			once.Do(func() {
+				h.Remove()
+				close(removed)
+			})
+		}
+	}))
+
+	task := root.Task("Panic")
+	task.Warning("Panics")
+
+	select {
+	case <-removed:
+	case <-time.After(2 * time.Second):
+		require.Fail("Removing quarantined driver deadlocked")
+	}
+
+	task.Info("Removed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := root.StopContext(ctx)
+	require.Nil(err)
+
+	require.Len(faulty.events, 1)
+
+}
+
+func Test_Root_AsyncDriver(t *testing.T) {
+	require := require.New(t)
+
+	slow := &blockingoutput{gate: make(chan struct{})}
+	fast := &captureoutput{}
+
+	root := NewRoot(8)
+	root.AddAsyncOutputDriver(slow, &QueueOptions{Size: 2, Overflow: OverflowDropNewest})
+	root.AddOutputDriver(fast)
+
+	task := root.Task("Async")
+	for i := 0; i < 10; i++ {
+		task.Info("Event", D{"I": i})
+	}
+
+	// The fast driver receives everything despite the slow one
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	require.Nil(root.queue.flush(ctx))
+	cancel()
+	require.Equal(11, len(fast.events))
+
+	close(slow.gate)
+	require.Nil(root.Flush(context.Background()))
+	root.Stop()
+
+	require.True(len(slow.events) < 11)
+
+	var last int64 = -1
+	for _, e := range slow.events {
+		if i, ok := e.Data["I"]; ok {
+			require.True(int64(i.(EventDataInt64)) > last)
+			last = int64(i.(EventDataInt64))
+		}
+	}
+
+	// Including the dropped event report
+	require.Equal(12, len(fast.events))
+
+}
+
+func Test_Root_Sync(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	task := root.Task("Sync")
+	require.Len(capture.events, 1)
+
+	task.Info("Inline")
+	require.Len(capture.events, 2)
+	require.Equal("Inline", capture.events[1].Message)
+
+	root.Stop()
+	root.Stop()
+
+	task.Success()
+	require.Len(capture.events, 2)
+	require.Equal(uint64(1), root.Dropped())
+
+}
+
+func Test_Root_Processors(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	root.AddProcessor(ProcessorFunc(func(e *Event) (*Event, bool) {
+		return e, e.Component != "noisy"
+	}))
+
+	root.AddProcessor(ProcessorFunc(func(e *Event) (*Event, bool) {
+		if _, ok := e.Data["Password"]; ok {
+			e.Data["Password"] = EventDataString("<redacted>")
+		}
+		return e, true
+	}))
+
+	root.AddProcessor(ProcessorFunc(func(e *Event) (*Event, bool) {
+		if e.Message == "Panic" {
+			panic("Processor failure")
+		}
+		return e, true
+	}))
+
+	root.Component("noisy").Info("Dropped")
+
+	task := root.Task("Login")
+	task.Info("Credentials", D{"User": "alice", "Password": "hunter2"})
+	task.Info("Panic")
+
+	require.Len(capture.events, 3)
+	require.Equal(EventDataString("<redacted>"), capture.events[1].Data["Password"])
+	require.Equal("Panic", capture.events[2].Message)
+
+	root.Stop()
+
+}
+
+func Test_Root_Static(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+	buffer := new(bytes.Buffer)
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+	root.AddOutputDriver(NewJSONOutput(buffer))
+
+	root.SetStatic(D{"Program": "test", "Labels": D{"Region": "east"}})
+	root.AddStatic(D{"PID": 7})
+
+	root.Task("Static").Info("Attributed", D{"X": 1})
+
+	e := capture.events[1]
+	require.Equal(EventDataString("test"), e.Static["Program"])
+	require.Equal(EventDataInt64(7), e.Static["PID"])
+	require.Nil(e.Data["Program"])
+
+	require.Contains(buffer.String(),
+		`"Static":{"Labels":{"Region":"east"},"PID":7,"Program":"test"}`)
+
+	root.Stop()
+
+}
+
+func Test_Root_StopContextBlocked(t *testing.T) {
+	require := require.New(t)
+
+	output := &blockingoutput{gate: make(chan struct{})}
+	defer close(output.gate)
+
+	root := NewRoot(1)
+	root.AddOutputDriver(output)
+
+	task := root.Task("Stuck")
+	go func() {
+		for i := 0; i < 5; i++ {
+			task.Info("Blocked")
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	stopped := make(chan error)
+	go func() {
+		_, err := root.StopContext(ctx)
+		stopped <- err
+	}()
+
+	select {
+	case err := <-stopped:
+		require.Equal(context.DeadlineExceeded, err)
+	case <-time.After(2 * time.Second):
+		require.Fail("StopContext ignored its deadline")
+	}
+
+	// Logging does not block once stopped
+	logged := make(chan struct{})
+	go func() {
+		task.Info("After stop")
+		close(logged)
+	}()
+
+	select {
+	case <-logged:
+	case <-time.After(2 * time.Second):
+		require.Fail("Logging blocked after stop")
+	}
+
+}
+
+func Test_Root_FallbackAbandoned(t *testing.T) {
+	require := require.New(t)
+
+	output := &blockingoutput{gate: make(chan struct{})}
+	fallback := &captureoutput{}
+
+	root := NewRoot(1)
+	root.AddOutputDriver(output)
+	root.SetFallbackDriver(fallback)
+
+	task := root.Task("Stuck")
+	task.Info("Blocked")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	_, err := root.StopContext(ctx)
+	cancel()
+	require.Equal(context.DeadlineExceeded, err)
+
+	// Events are dropped while the Root has not finished stopping
+	logged := make(chan struct{})
+	go func() {
+		task.Info("Abandoned")
+		close(logged)
+	}()
+
+	select {
+	case <-logged:
+	case <-time.After(2 * time.Second):
+		require.Fail("Logging blocked on the fallback driver")
+	}
+
+	require.Empty(fallback.events)
+	require.Equal(uint64(1), root.Dropped())
+
+	close(output.gate)
+	root.Stop()
+
+	task.Info("Late")
+	require.Len(fallback.events, 1)
+
+}
+
+// reentrantoutput logs to its own Root upon each event from another
+// component.
+type reentrantoutput struct {
+	captureoutput
+	task *Task
+}
+
+func (x *reentrantoutput) Event(event *Event) {
+	x.captureoutput.Event(event)
+	if event.Component != "echo" {
+		x.task.Info("Echo", D{"Of": event.Message})
+	}
+}
+
+func Test_Root_SyncReentrant(t *testing.T) {
+	require := require.New(t)
+
+	output := &reentrantoutput{}
+
+	root := NewSyncRoot()
+	output.task = root.Component("echo")
+	root.AddOutputDriver(output)
+	root.SetErrorListener(errorfunc(func(err error) {
+		output.task.Info("Listener")
+	}))
+
+	task := root.Component("main")
+	done := make(chan struct{})
+	go func() {
+		task.Info("First")
+		task.Info("Second")
+		root.InternalError(NewError("Oops"))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		require.Fail("Reentrant logging deadlocked")
+	}
+
+	// Each echo follows the event that triggered it
+	require.Len(output.events, 7)
+	require.Equal(BEGIN, output.events[0].Event)
+	require.Equal("First", output.events[2].Message)
+	require.Equal(EventDataString("First"), output.events[3].Data["Of"])
+	require.Equal("Second", output.events[4].Message)
+	require.Equal(EventDataString("Second"), output.events[5].Data["Of"])
+	require.Equal("Listener", output.events[6].Message)
+
+	root.Stop()
+
+}
+
+// enteredoutput signals once it has received its first event, then
+// blocks on its gate as blockingoutput.
+type enteredoutput struct {
+	blockingoutput
+	entered chan struct{}
+	once    sync.Once
+}
+
+func (x *enteredoutput) Event(event *Event) {
+	x.once.Do(func() { close(x.entered) })
+	x.blockingoutput.Event(event)
+}
+
+func Test_Root_SyncConcurrent(t *testing.T) {
+	require := require.New(t)
+
+	output := &enteredoutput{
+		blockingoutput: blockingoutput{gate: make(chan struct{})},
+		entered:        make(chan struct{}),
+	}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(output)
+
+	go root.Task("First")
+	<-output.entered
+
+	done := make(chan struct{})
+	go func() {
+		root.Task("Second")
+		close(done)
+	}()
+
+	// The second goroutine waits for the first event to be output
+	select {
+	case <-done:
+		require.Fail("Concurrent event returned before being output")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(output.gate)
+	<-done
+
+	// and then outputs its own before returning
+	output.lock.Lock()
+	require.Len(output.events, 2)
+	require.Equal("Second begin", output.events[1].Message)
+	output.lock.Unlock()
+
+	require.Nil(root.Flush(context.Background()))
+	root.Stop()
+
+}
+
+type errorfunc func(err error)
+
+func (x errorfunc) Error(err error) {
+	x(err)
+}
+
+func Test_Root_DropOldestUnbuffered(t *testing.T) {
+	require := require.New(t)
+
+	output := &blockingoutput{gate: make(chan struct{})}
+
+	root := NewRoot(0)
+	root.AddOutputDriver(output)
+	root.SetOverflowPolicy(OverflowDropOldest, 0)
+
+	task := root.Task("Flood")
+
+	flooded := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			task.Info("Flooding")
+		}
+		close(flooded)
+	}()
+
+	select {
+	case <-flooded:
+	case <-time.After(2 * time.Second):
+		require.Fail("Unbuffered drop-oldest blocked")
+	}
+
+	close(output.gate)
+	root.Stop()
+
+	require.True(root.Dropped() > 0)
+
+}
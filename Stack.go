@@ -0,0 +1,143 @@
+package logberry
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Frame is a single function invocation in a Stack.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Stack is a call stack captured by an Error, innermost call first.
+// As event data it is written compactly as function@file:line entries
+// with only the base name of each file, but is a full array of frame
+// structures in JSON.
+type Stack []Frame
+
+// maxstackdepth bounds the number of frames captured in a Stack.
+const maxstackdepth = 32
+
+var stacktraces int32
+
+// SetStackTraces enables or disables capturing a Stack on every Error
+// generated by NewError, WrapError, and Task.Failure, for all Roots.
+// It is disabled by default.  Roots may also enable stack traces for
+// their own Tasks individually via Root.SetStackTraces.
+func SetStackTraces(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&stacktraces, v)
+}
+
+// StackTraces reports whether stack traces are enabled for all Roots.
+func StackTraces() bool {
+	return atomic.LoadInt32(&stacktraces) != 0
+}
+
+// CaptureStack returns the call stack from the point at which it was
+// called, skipping the given number of additional frames.
+func CaptureStack(skip int) Stack {
+
+	pcs := make([]uintptr, maxstackdepth)
+	n := runtime.Callers(skip+2, pcs)
+	if n == 0 {
+		return nil
+	}
+
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := make(Stack, 0, n)
+	for {
+		f, more := frames.Next()
+		stack = append(stack, Frame{
+			Function: f.Function,
+			File:     f.File,
+			Line:     f.Line,
+		})
+		if !more {
+			break
+		}
+	}
+
+	return stack
+
+}
+
+func (x Stack) WriteTo(out io.Writer) {
+
+	fmt.Fprintf(out, "[")
+
+	for i, f := range x {
+		if i > 0 {
+			fmt.Fprintf(out, " ")
+		}
+		fmt.Fprintf(out, "%v@%v:%v", f.Function, filepath.Base(f.File), f.Line)
+	}
+
+	fmt.Fprintf(out, "]")
+
+}
+
+var mainmodule struct {
+	once sync.Once
+	path string
+}
+
+// trimpath returns the given source file, from the given function,
+// relative to the root of its module.  The file's module is taken to
+// be the main module if the function's package is within it, in which
+// case the path is relative to that module's root, and otherwise the
+// path is prefixed by the package's import path.  Files within the
+// main package are reduced to their base names.
+func trimpath(function, file string) string {
+
+	if function == "" || file == "" {
+		return file
+	}
+
+	// The package is the function name up to the first dot after the
+	// last slash, excluding any type parameters
+	pkg := function
+	if i := strings.Index(pkg, "["); i >= 0 {
+		pkg = pkg[:i]
+	}
+	slash := strings.LastIndex(pkg, "/")
+	if i := strings.Index(pkg[slash+1:], "."); i >= 0 {
+		pkg = pkg[:slash+1+i]
+	}
+
+	base := filepath.Base(file)
+	if pkg == "main" {
+		return base
+	}
+
+	mainmodule.once.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			mainmodule.path = info.Main.Path
+		}
+	})
+
+	if m := mainmodule.path; m != "" {
+		if pkg == m {
+			return base
+		}
+		if strings.HasPrefix(pkg, m+"/") {
+			return pkg[len(m)+1:] + "/" + base
+		}
+	}
+
+	return pkg + "/" + base
+
+}
@@ -0,0 +1,93 @@
+package logberry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_Stack_Disabled(t *testing.T) {
+	require := require.New(t)
+
+	require.False(StackTraces())
+	require.Nil(NewError("No stack").Stack)
+
+	root := NewSyncRoot()
+	require.Nil(root.Task("Quiet").Failure("No stack").Cause.(*Error).Stack)
+	root.Stop()
+
+}
+
+func Test_Stack_Global(t *testing.T) {
+	require := require.New(t)
+
+	SetStackTraces(true)
+	defer SetStackTraces(false)
+
+	e := NewError("Traced")
+	require.NotEmpty(e.Stack)
+	require.Equal("github.com/BellerophonMobile/logberry.Test_Stack_Global", e.Stack[0].Function)
+
+	w := WrapError("Wrapped", e)
+	require.NotEmpty(w.Stack)
+
+	s := fmt.Sprintf("%+v", e)
+	require.Regexp(`\n    stack\n        github.com/BellerophonMobile/logberry.Test_Stack_Global\n            Stack_test.go:\d+\n`, s)
+	require.Equal("Traced", fmt.Sprintf("%v", e))
+
+}
+
+func Test_Stack_Root(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+	root.SetStackTraces(true)
+
+	err := root.Task("Compute").Failure("Overflow")
+	root.Stop()
+
+	stack := err.Cause.(*Error).Stack
+	require.NotEmpty(stack)
+	require.Nil(NewError("Untraced").Stack)
+
+	cause := capture.events[1].Data["Cause"].(EventDataMap)
+	require.Equal(stack, cause["Stack"])
+
+	var text bytes.Buffer
+	stack[:1].WriteTo(&text)
+	require.Regexp(`^\[github.com/BellerophonMobile/logberry.Test_Stack_Root@Stack_test.go:\d+\]$`, text.String())
+
+	js, err2 := json.Marshal(stack[:1])
+	require.Nil(err2)
+	require.Regexp(`^\[\{"Function":"github.com/BellerophonMobile/logberry.Test_Stack_Root","File":".*Stack_test.go","Line":\d+\}\]$`, string(js))
+
+}
+
+func Test_Stack_TaskErrors(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+	root.SetStackTraces(true)
+
+	err := root.Task("Read").Error(NewError("Disk failure"))
+	require.NotEmpty(err.Stack)
+	require.Equal("github.com/BellerophonMobile/logberry.Test_Stack_TaskErrors", err.Stack[0].Function)
+
+	wrapped := root.Task("Write").WrapError("Could not write", NewError("Disk full"))
+	stack := wrapped.Cause.(*Error).Stack
+	require.NotEmpty(stack)
+	require.Equal("github.com/BellerophonMobile/logberry.Test_Stack_TaskErrors", stack[0].Function)
+
+	root.Stop()
+
+	require.Equal(err.Stack, capture.match(ERROR, 2)[0].Data["Stack"])
+	cause := capture.match(ERROR, 2)[1].Data["Cause"].(EventDataMap)
+	require.Equal(stack, cause["Stack"])
+
+}
@@ -2,6 +2,7 @@ package logberry
 
 import (
 	"sync/atomic"
+	"time"
 )
 
 // Task represents a particular component, function, or activity.  In
@@ -9,7 +10,7 @@ import (
 // execution, and the calling code is responsible for managing any
 // concurrent manipulation.
 type Task struct {
-	uid uint64
+	uid ID
 
 	root *Root
 
@@ -20,46 +21,99 @@ type Task struct {
 	activity string
 
 	data EventDataMap
-}
 
-var numtasks uint64
+	start time.Time
+
+	concluded int32
+	unwatch   atomic.Value // func() bool
 
-func newtaskuid() uint64 {
-	// We have seen this atomic call cause problems on ARM...
-	return atomic.AddUint64(&numtasks, 1) - 1
+	trace      TraceContext
+	parentspan SpanID
 }
 
-func newtask(parent *Task, component string, activity string, data []interface{}) *Task {
+func newtask(root *Root, parent *Task, component string, activity string, data []interface{}, remote *TraceContext) *Task {
 
 	t := &Task{
-		uid:      newtaskuid(),
+		root:     root,
 		parent:   parent,
 		activity: activity,
 		data:     EventDataMap{},
+		start:    time.Now(),
 	}
 
 	if parent != nil {
 		t.root = parent.root
 		t.component = parent.component
-	} else {
+	} else if t.root == nil {
 		t.root = Std
 	}
 
+	t.uid = t.root.newid()
+	t.inherittrace(remote)
+
 	if component != "" {
 		t.component = component
 	}
 
-	t.root.event(t, BEGIN, t.activity+" begin", Aggregate(data))
+	if t.Enabled(LevelInfo) {
+		t.root.event(t, BEGIN, t.activity+" begin", Aggregate(data))
+	}
 
 	return t
 
 }
 
+// conclude generates an event reporting the end of the Task's
+// activity, annotated with the time elapsed since the Task began.
+func (x *Task) conclude(event string, msg string, data EventDataMap) {
+	if x.concluding(event) {
+		x.endevent(event, msg, data)
+	}
+}
+
+// concluding marks the Task as concluded, and reports whether a
+// concluding event of the given class is to be generated.  It is not
+// if the Task's context was done first, or the event's Level is not
+// enabled.
+func (x *Task) concluding(event string) bool {
+	return x.finish() && x.Enabled(EventLevel(event))
+}
+
+// endevent pushes a concluding event, annotated with the time elapsed
+// since the Task began.
+func (x *Task) endevent(event string, msg string, data EventDataMap) {
+	e := x.root.newevent(x, event, msg, data)
+	e.Duration = time.Since(x.start)
+	x.root.push(e)
+}
+
+// Elapsed returns the time since the Task began.
+func (x *Task) Elapsed() time.Duration {
+	return time.Since(x.start)
+}
+
+// ID returns the identifier of the Task, as reported in its events.
+func (x *Task) ID() ID {
+	return x.uid
+}
+
+// Root returns the Root to which the Task's events are pushed.
+func (x *Task) Root() *Root {
+	return x.root
+}
+
+// Enabled reports whether events of the given Level generated by this
+// Task are forwarded to output drivers by its Root.  It may be used to
+// skip computing expensive data for events that would be dropped.
+func (x *Task) Enabled(level Level) bool {
+	return x.root.Enabled(x.component, level)
+}
+
 // Task creates a new sub-task.  Parameter activity should be a short
 // natural language description of the work that the Task represents,
 // without any terminating punctuation.
 func (x *Task) Task(activity string, data ...interface{}) *Task {
-	return newtask(x, "", activity, data)
+	return newtask(nil, x, "", activity, data, nil)
 }
 
 // Component creates a new Task object representing related long-lived
@@ -69,7 +123,7 @@ func (x *Task) Task(activity string, data ...interface{}) *Task {
 // Task represents.  The activity text of this Task is set to be
 // "Component " + component.
 func (x *Task) Component(component string, data ...interface{}) *Task {
-	return newtask(x, component, "Component "+component, data)
+	return newtask(nil, x, component, "Component "+component, data, nil)
 }
 
 // AddData incorporates the given data into that associated and
@@ -94,6 +148,9 @@ func (x *Task) AddData(data ...interface{}) *Task {
 // event, as is the data permanently associated with the Task.  The
 // given data is not associated to the Task permanently.
 func (x *Task) Event(event string, msg string, data ...interface{}) {
+	if !x.Enabled(EventLevel(event)) {
+		return
+	}
 	x.root.event(x, event, msg, Aggregate(data).Aggregate(x.data))
 }
 
@@ -106,9 +163,37 @@ func (x *Task) Event(event string, msg string, data ...interface{}) {
 // associated with the Task.  The given data is not associated to the
 // Task permanently.
 func (x *Task) Info(msg string, data ...interface{}) {
+	if !x.Enabled(LevelInfo) {
+		return
+	}
 	x.root.event(x, INFO, msg, Aggregate(data).Aggregate(x.data))
 }
 
+// Debug generates a debugging log event, intended for detailed
+// diagnostic output that is typically disabled in production.  A
+// human-oriented text message is given as the msg parameter, as with
+// Info.  The variadic data parameter is aggregated as a D and reported
+// with the event, as is the data permanently associated with the
+// Task.  No data is aggregated if the event is below the minimum Level
+// configured on the Root.  The given data is not associated to the
+// Task permanently.
+func (x *Task) Debug(msg string, data ...interface{}) {
+	if !x.Enabled(LevelDebug) {
+		return
+	}
+	x.root.event(x, DEBUG, msg, Aggregate(data).Aggregate(x.data))
+}
+
+// Trace generates a tracing log event, intended for very fine grained
+// diagnostic output such as hot path execution.  It is otherwise the
+// same as Debug.
+func (x *Task) Trace(msg string, data ...interface{}) {
+	if !x.Enabled(LevelTrace) {
+		return
+	}
+	x.root.event(x, TRACE, msg, Aggregate(data).Aggregate(x.data))
+}
+
 // Warning generates a warning log event indicating that a fault was
 // encountered but the task is proceeding acceptably.  This should
 // generally be static, short, use sentence capitalization but no
@@ -118,6 +203,9 @@ func (x *Task) Info(msg string, data ...interface{}) {
 // permanently associated with the Task.  The given data is not
 // associated to the Task permanently.
 func (x *Task) Warning(msg string, data ...interface{}) {
+	if !x.Enabled(LevelWarning) {
+		return
+	}
 	x.root.event(x, WARNING, msg, Aggregate(data).Aggregate(x.data))
 }
 
@@ -127,6 +215,9 @@ func (x *Task) Warning(msg string, data ...interface{}) {
 // the event, as is the data permanently associated with the Task.
 // The given data is not associated to the Task permanently.
 func (x *Task) Ready(data ...interface{}) {
+	if !x.Enabled(LevelInfo) {
+		return
+	}
 	x.root.event(x, READY, x.activity+" ready", Aggregate(data).Aggregate(x.data))
 }
 
@@ -136,7 +227,7 @@ func (x *Task) Ready(data ...interface{}) {
 // event, as is the data permanently associated with the Task.  The
 // given data is not associated to the Task permanently.
 func (x *Task) Stopped(data ...interface{}) {
-	x.root.event(x, STOPPED, x.activity+" stopped", Aggregate(data).Aggregate(x.data))
+	x.conclude(STOPPED, x.activity+" stopped", Aggregate(data).Aggregate(x.data))
 }
 
 // Finalized generates an end log event reporting that the component
@@ -147,7 +238,7 @@ func (x *Task) Stopped(data ...interface{}) {
 // the data permanently associated with the Task.  The given data is
 // not associated to the Task permanently.
 func (x *Task) Finalized(data ...interface{}) {
-	x.root.event(x, END, x.activity+" finalized", Aggregate(data).Aggregate(x.data))
+	x.conclude(END, x.activity+" finalized", Aggregate(data).Aggregate(x.data))
 }
 
 // Success generates a success log event reporting that the activity
@@ -157,7 +248,7 @@ func (x *Task) Finalized(data ...interface{}) {
 // the data permanently associated with the Task.  The given data is
 // not associated to the Task permanently.
 func (x *Task) Success(data ...interface{}) error {
-	x.root.event(x, SUCCESS, x.activity+" success", Aggregate(data).Aggregate(x.data))
+	x.conclude(SUCCESS, x.activity+" success", Aggregate(data).Aggregate(x.data))
 	return nil
 }
 
@@ -176,32 +267,28 @@ func (x *Task) Error(cause error, data ...interface{}) *Error {
 	m := x.activity + " failed"
 	taskerr := wraperror(m, cause, data)
 	taskerr.Locate(1) // Locate up the call stack
+	if x.root.StackTraces() {
+		taskerr.Stack = CaptureStack(1)
+	}
+
+	if !x.concluding(ERROR) {
+		return taskerr
+	}
 
 	taskerr.Reported = true
 
 	d := Aggregate(data).Aggregate(D{"Source": taskerr.Source})
+	if taskerr.Stack != nil {
+		d["Stack"] = Copy(taskerr.Stack)
+	}
 
-	dsub := d
-	for cursor := cause; cursor != nil; {
-		if ce, ok := cursor.(*Error); ok {
-			if !ce.Reported {
-				ce.Reported = true
-				next := EventDataMap{}.Aggregate(ce)
-				dsub["Cause"] = next
-				dsub = next
-				cursor = ce.Cause
-			} else {
-				break
-			}
-		} else {
-			dsub["Cause"] = Copy(cursor)
-			break
-		}
+	if c := causedata(cause); c != nil {
+		d["Cause"] = c
 	}
 
 	d.Aggregate(x.data)
 
-	x.root.event(x, ERROR, m, d)
+	x.endevent(ERROR, m, d)
 
 	return taskerr
 
@@ -210,12 +297,18 @@ func (x *Task) Error(cause error, data ...interface{}) *Error {
 func (x *Task) WrapError(msg string, cause error, data ...interface{}) *Error {
 
 	usererr := wraperror(msg, cause, nil)
-	usererr.Reported = true
 	usererr.Locate(1)
+	if x.root.StackTraces() {
+		usererr.Stack = CaptureStack(1)
+	}
 
 	m := x.activity + " failed"
 	taskerr := wraperror(m, usererr, data)
 
+	if !x.concluding(ERROR) {
+		return taskerr
+	}
+
 	usererr.Reported = true
 	taskerr.Reported = true
 
@@ -224,26 +317,13 @@ func (x *Task) WrapError(msg string, cause error, data ...interface{}) *Error {
 	dsub := Aggregate([]interface{}{usererr})
 	d["Cause"] = dsub
 
-	for cursor := cause; cursor != nil; {
-		if ce, ok := cursor.(*Error); ok {
-			if !ce.Reported {
-				ce.Reported = true
-				next := EventDataMap{}.Aggregate(ce)
-				dsub["Cause"] = next
-				dsub = next
-				cursor = ce.Cause
-			} else {
-				break
-			}
-		} else {
-			dsub["Cause"] = Copy(cursor)
-			break
-		}
+	if c := causedata(cause); c != nil {
+		dsub["Cause"] = c
 	}
 
 	d.Aggregate(x.data)
 
-	x.root.event(x, ERROR, m, d)
+	x.endevent(ERROR, m, d)
 
 	return taskerr
 
@@ -265,10 +345,17 @@ func (x *Task) Failure(msg string, data ...interface{}) *Error {
 
 	cause := newerror(msg, nil)
 	cause.Locate(1)
+	if x.root.StackTraces() {
+		cause.Stack = CaptureStack(1)
+	}
 
 	m := x.activity + " failed"
 	taskerr := wraperror(m, cause, data)
 
+	if !x.concluding(ERROR) {
+		return taskerr
+	}
+
 	cause.Reported = true
 	taskerr.Reported = true
 
@@ -276,7 +363,7 @@ func (x *Task) Failure(msg string, data ...interface{}) *Error {
 	d["Cause"] = Copy(cause)
 	d.Aggregate(x.data)
 
-	x.root.event(x, ERROR, m, d)
+	x.endevent(ERROR, m, d)
 
 	return taskerr
 
@@ -1,8 +1,11 @@
 package logberry
 
 import (
+	"context"
 	"github.com/stretchr/testify/require"
+	"sync"
 	"testing"
+	"time"
 )
 
 func Test_Failure(t *testing.T) {
@@ -22,3 +25,180 @@ func Test_Failure(t *testing.T) {
 	require.Equal(num, EventDataInt64(404))
 
 }
+
+type captureoutput struct {
+	lock   sync.Mutex
+	events []*Event
+}
+
+func (x *captureoutput) Attach(root *Root) {}
+func (x *captureoutput) Detach()           {}
+
+func (x *captureoutput) Event(event *Event) {
+	x.lock.Lock()
+	x.events = append(x.events, event)
+	x.lock.Unlock()
+}
+
+// match returns the captured events of the given class, waiting up to
+// a second for there to be at least n of them.
+func (x *captureoutput) match(event string, n int) []*Event {
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		var matches []*Event
+
+		x.lock.Lock()
+		for _, e := range x.events {
+			if e.Event == event {
+				matches = append(matches, e)
+			}
+		}
+		x.lock.Unlock()
+
+		if len(matches) >= n || time.Now().After(deadline) {
+			return matches
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+}
+
+func Test_Duration(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+	root := NewRoot(8)
+	root.AddOutputDriver(capture)
+
+	task := root.Task("Sleep")
+	time.Sleep(10 * time.Millisecond)
+	task.Success()
+
+	root.Stop()
+
+	require.Len(capture.events, 2)
+	require.Equal(BEGIN, capture.events[0].Event)
+	require.Zero(capture.events[0].Duration)
+	require.Equal(SUCCESS, capture.events[1].Event)
+	require.True(capture.events[1].Duration >= 10*time.Millisecond)
+
+}
+
+func Test_Context(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	require.Equal(Main, FromContext(context.Background()))
+
+	parent := root.Task("Parent")
+	ctx := WithTask(context.Background(), parent)
+	require.Equal(parent, FromContext(ctx))
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	task, taskctx := FromContext(ctx).TaskContext(ctx, "Slow")
+	require.Equal(task, FromContext(taskctx))
+
+	done, _ := parent.TaskContext(ctx, "Fast")
+	done.Success()
+
+	<-taskctx.Done()
+	errors := capture.match(ERROR, 1)
+	root.Stop()
+
+	require.Len(errors, 1)
+	require.Equal(task.ID(), errors[0].TaskID)
+	require.Equal("Slow failed", errors[0].Message)
+
+}
+
+func Test_Context_Concluded(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	task, _ := root.Task("Parent").TaskContext(ctx, "Busy")
+
+	// Data may be added while the cancellation is reported
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			task.AddData(D{"I": i})
+		}
+		close(done)
+	}()
+
+	cancel()
+	<-done
+
+	errors := capture.match(ERROR, 1)
+	task.Success()
+	task.Failure("Too late")
+	root.Stop()
+
+	require.Len(errors, 1)
+	require.Empty(capture.match(SUCCESS, 0))
+	require.Len(capture.match(ERROR, 1), 1)
+
+}
+
+func Test_Concluded_Repeatedly(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	c := root.Component("svc")
+	c.Stopped()
+	c.Ready()
+	c.Finalized()
+
+	task := root.Task("Retry")
+	task.Success()
+	task.Failure("Later")
+	root.Stop()
+
+	require.Len(capture.match(STOPPED, 1), 1)
+	require.Len(capture.match(END, 1), 1)
+	require.Len(capture.match(SUCCESS, 1), 1)
+	require.Len(capture.match(ERROR, 1), 1)
+
+}
+
+func Test_Context_Unreported(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	task, _ := root.Task("Parent").TaskContext(ctx, "Busy")
+
+	cancel()
+	capture.match(ERROR, 1)
+
+	cause := NewError("Disk full")
+	err := task.Error(cause)
+	root.Stop()
+
+	require.False(cause.Reported)
+	require.False(err.Reported)
+	require.Len(capture.match(ERROR, 1), 1)
+
+}
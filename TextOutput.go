@@ -80,6 +80,8 @@ var eventstyles = map[string]terminalstyle{
 	SUCCESS:       {white, false, highintensity},   // end
 	WARNING:       {yellow, false, highintensity},  // warning
 	ERROR:         {red, true, highintensity},      // error
+	DEBUG:         {black, false, highintensity},   // debug
+	TRACE:         {black, false, highintensity},   // trace
 }
 
 // NewStdOutput creates a new TextOutput attached to stdout.
@@ -185,6 +187,16 @@ func (o *TextOutput) Event(event *Event) {
 	}
 	writsofar += n
 
+	// Write the elapsed time, if the event concludes a task
+	if event.Duration > 0 {
+		n, e = fmt.Fprintf(o.writer, "(%v) ", event.Duration)
+		if e != nil {
+			o.root.InternalError(WrapError("Could write entry", e))
+			return
+		}
+		writsofar += n
+	}
+
 	// Space out and then write the data fields
 	for writsofar < o.IDOffset {
 		n, _ = fmt.Fprintf(o.writer, " ")
@@ -230,6 +242,40 @@ func (o *TextOutput) Event(event *Event) {
 		event.Data.WriteTo(o.writer)
 	}
 
+	if len(event.Static) > 0 {
+		_, e = fmt.Fprintf(o.writer, " static=")
+		if e != nil {
+			o.root.InternalError(WrapError("Could write entry", e))
+			return
+		}
+		event.Static.WriteTo(o.writer)
+	}
+
+	// Write the run and trace identifiers, if any
+	if event.RunID != "" {
+		_, e = fmt.Fprintf(o.writer, " run=%v", event.RunID)
+		if e != nil {
+			o.root.InternalError(WrapError("Could write entry", e))
+			return
+		}
+	}
+
+	if event.TraceID != "" {
+		_, e = fmt.Fprintf(o.writer, " trace=%v span=%v", event.TraceID, event.SpanID)
+		if e != nil {
+			o.root.InternalError(WrapError("Could write entry", e))
+			return
+		}
+
+		if event.ParentSpanID != "" {
+			_, e = fmt.Fprintf(o.writer, " parentspan=%v", event.ParentSpanID)
+			if e != nil {
+				o.root.InternalError(WrapError("Could write entry", e))
+				return
+			}
+		}
+	}
+
 	if o.Color {
 		_, e := fmt.Fprintf(o.writer, "\x1b[0m")
 		if e != nil {
@@ -0,0 +1,33 @@
+package logberry
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func Test_TextOutput_IDs(t *testing.T) {
+	require := require.New(t)
+
+	buffer := new(bytes.Buffer)
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(NewTextOutput(buffer, "test"))
+
+	remote, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Nil(err)
+
+	task := root.RemoteTask("Request", remote)
+	task.Info("Handled")
+	root.Stop()
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(lines, 2)
+
+	line := lines[1]
+	require.Contains(line, " run="+string(RunID))
+	require.Contains(line, " trace=4bf92f3577b34da6a3ce929d0e0e4736 span="+task.TraceContext().SpanID.String())
+	require.Contains(line, " parentspan=00f067aa0ba902b7")
+
+}
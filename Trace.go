@@ -0,0 +1,213 @@
+package logberry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceID identifies a distributed trace, per the W3C Trace Context
+// specification.
+type TraceID [16]byte
+
+// SpanID identifies a span within a distributed trace, per the W3C
+// Trace Context specification.  Each traced Task is a span.
+type SpanID [8]byte
+
+// IsValid reports whether the TraceID is not all zeroes.
+func (x TraceID) IsValid() bool {
+	return x != TraceID{}
+}
+
+// String returns the TraceID as 32 lowercase hexadecimal digits.
+func (x TraceID) String() string {
+	return hex.EncodeToString(x[:])
+}
+
+// IsValid reports whether the SpanID is not all zeroes.
+func (x SpanID) IsValid() bool {
+	return x != SpanID{}
+}
+
+// String returns the SpanID as 16 lowercase hexadecimal digits.
+func (x SpanID) String() string {
+	return hex.EncodeToString(x[:])
+}
+
+// The HTTP headers carrying trace context.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// TraceSampled is the trace flag indicating the trace may be recorded.
+const TraceSampled byte = 0x01
+
+// TraceContext is the propagated identity of a span in a distributed
+// trace, as carried by the W3C traceparent and tracestate headers.
+type TraceContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+
+	// State is the opaque vendor specific tracestate header, passed
+	// through unmodified.
+	State string
+}
+
+// IsValid reports whether both the TraceID and SpanID are valid.
+func (x TraceContext) IsValid() bool {
+	return x.TraceID.IsValid() && x.SpanID.IsValid()
+}
+
+// Traceparent returns the TraceContext encoded as a version 00 W3C
+// traceparent header value.
+func (x TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%v-%v-%02x", x.TraceID, x.SpanID, x.Flags)
+}
+
+// Inject sets the traceparent and tracestate headers from the
+// TraceContext.  Nothing is set if it is not valid.
+func (x TraceContext) Inject(header http.Header) {
+
+	if !x.IsValid() {
+		return
+	}
+
+	header.Set(TraceparentHeader, x.Traceparent())
+
+	if x.State != "" {
+		header.Set(TracestateHeader, x.State)
+	} else {
+		header.Del(TracestateHeader)
+	}
+
+}
+
+// Extract reads a TraceContext from the traceparent and tracestate
+// headers.  It returns false if there is no valid traceparent header,
+// in which case the tracestate header is also ignored.
+func Extract(header http.Header) (TraceContext, bool) {
+
+	tc, err := ParseTraceparent(header.Get(TraceparentHeader))
+	if err != nil {
+		return TraceContext{}, false
+	}
+
+	tc.State = strings.Join(header.Values(TracestateHeader), ",")
+
+	return tc, true
+
+}
+
+// ParseTraceparent decodes a W3C traceparent header value.  Values of
+// future versions are accepted so long as they begin with the fields
+// of version 00, as the specification requires.
+func ParseTraceparent(value string) (TraceContext, error) {
+
+	var tc TraceContext
+
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return tc, NewError("Malformed traceparent", D{"Traceparent": value})
+	}
+
+	version := value[:2]
+	if !islowerhex(version) || version == "ff" ||
+		(version == "00" && len(value) != 55) ||
+		(len(value) > 55 && value[55] != '-') {
+		return tc, NewError("Invalid traceparent version", D{"Traceparent": value})
+	}
+
+	traceid, spanid, flags := value[3:35], value[36:52], value[53:55]
+	if !islowerhex(traceid) || !islowerhex(spanid) || !islowerhex(flags) {
+		return tc, NewError("Malformed traceparent", D{"Traceparent": value})
+	}
+
+	hex.Decode(tc.TraceID[:], []byte(traceid))
+	hex.Decode(tc.SpanID[:], []byte(spanid))
+
+	var f [1]byte
+	hex.Decode(f[:], []byte(flags))
+	tc.Flags = f[0]
+
+	if !tc.IsValid() {
+		return TraceContext{}, NewError("Invalid traceparent identifiers",
+			D{"Traceparent": value})
+	}
+
+	return tc, nil
+
+}
+
+func islowerhex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newtraceid() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newspanid() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// TraceContext returns the trace identity of the Task, which is not
+// valid if the Task is not part of a trace.  Passing it to another
+// service, e.g., via TraceContext.Inject on an outgoing request's
+// headers, enables that service to continue the trace.
+func (x *Task) TraceContext() TraceContext {
+	return x.trace
+}
+
+// RemoteTask creates a new sub-task as Task, but as the child in a
+// distributed trace of the given span, typically extracted from an
+// incoming request.  If the remote TraceContext is not valid then a
+// new trace is started with the created Task as its root.  Subsequent
+// sub-tasks of the created Task are also traced.
+func (x *Task) RemoteTask(activity string, remote TraceContext, data ...interface{}) *Task {
+	return newtask(nil, x, "", activity, data, &remote)
+}
+
+// RemoteTask creates a new top level Task under this Root as the
+// child in a distributed trace of the given span, as Task.RemoteTask.
+func (x *Root) RemoteTask(activity string, remote TraceContext, data ...interface{}) *Task {
+	return newtask(x, nil, "", activity, data, &remote)
+}
+
+// inherittrace sets the trace identity of a new Task, from the given remote
+// parent span if any, otherwise from its local parent.
+func (x *Task) inherittrace(remote *TraceContext) {
+
+	var parent TraceContext
+	if remote != nil {
+		parent = *remote
+		if !parent.IsValid() {
+			parent = TraceContext{TraceID: newtraceid(), Flags: TraceSampled}
+		}
+	} else if x.parent != nil && x.parent.trace.IsValid() {
+		parent = x.parent.trace
+	} else {
+		return
+	}
+
+	x.trace = parent
+	x.trace.SpanID = newspanid()
+	x.parentspan = parent.SpanID
+
+}
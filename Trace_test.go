@@ -0,0 +1,82 @@
+package logberry
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func Test_Trace_Parse(t *testing.T) {
+	require := require.New(t)
+
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tc, err := ParseTraceparent(value)
+	require.Nil(err)
+	require.Equal("4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID.String())
+	require.Equal("00f067aa0ba902b7", tc.SpanID.String())
+	require.Equal(TraceSampled, tc.Flags)
+	require.Equal(value, tc.Traceparent())
+
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	require.Nil(err)
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceparent(bad)
+		require.NotNil(err, bad)
+	}
+
+}
+
+func Test_Trace_Propagation(t *testing.T) {
+	require := require.New(t)
+
+	capture := &captureoutput{}
+
+	root := NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	incoming := http.Header{}
+	incoming.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incoming.Set(TracestateHeader, "vendor=value")
+
+	remote, ok := Extract(incoming)
+	require.True(ok)
+
+	server := root.RemoteTask("Handle request", remote)
+	client := server.Task("Call backend")
+
+	outgoing := http.Header{}
+	client.TraceContext().Inject(outgoing)
+
+	propagated, ok := Extract(outgoing)
+	require.True(ok)
+	require.Equal(remote.TraceID, propagated.TraceID)
+	require.Equal(client.TraceContext().SpanID, propagated.SpanID)
+	require.Equal("vendor=value", propagated.State)
+
+	begin := capture.events[0]
+	require.Equal(remote.TraceID.String(), begin.TraceID)
+	require.Equal(server.TraceContext().SpanID.String(), begin.SpanID)
+	require.Equal(remote.SpanID.String(), begin.ParentSpanID)
+
+	require.Equal(server.TraceContext().SpanID.String(), capture.events[1].ParentSpanID)
+
+	fresh := root.RemoteTask("New trace", TraceContext{})
+	require.True(fresh.TraceContext().IsValid())
+	require.NotEqual(remote.TraceID, fresh.TraceContext().TraceID)
+
+	untraced := root.Task("Untraced")
+	require.False(untraced.TraceContext().IsValid())
+	require.Equal("", capture.events[3].TraceID)
+
+	root.Stop()
+
+}
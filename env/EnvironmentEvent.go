@@ -124,3 +124,42 @@ func LogProcess(main *logberry.Task) error {
 	return nil
 
 }
+
+// ProcessData returns identifiers for the currently executing process
+// suitable for attaching to every event via Root.SetStatic, so that
+// merged logs from many programs may be attributed: the program name,
+// host, process ID, and the run ID distinguishing this execution from
+// others of the same program.
+func ProcessData() (logberry.D, error) {
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, logberry.WrapError("Could not retrieve hostname", err)
+	}
+
+	d := logberry.D{
+		"Program":  path.Base(os.Args[0]),
+		"Host":     hostname,
+		"PID":      os.Getpid(),
+		"Instance": string(logberry.RunID),
+	}
+
+	return d, nil
+
+}
+
+// SetProcessStatic attaches the identifiers returned by ProcessData,
+// along with the given additional data such as deployment labels, to
+// every event generated under the given Root.
+func SetProcessStatic(root *logberry.Root, data ...interface{}) error {
+
+	d, err := ProcessData()
+	if err != nil {
+		return err
+	}
+
+	root.SetStatic(append([]interface{}{d}, data...)...)
+
+	return nil
+
+}
@@ -1,9 +1,16 @@
 module github.com/BellerophonMobile/logberry
 
+go 1.21
+
 require (
 	github.com/BellerophonMobile/gocui v0.3.2
 	github.com/BellerophonMobile/sse v0.0.0-20161215130822-78f7721c7b46
+	github.com/stretchr/testify v1.2.2
+)
+
+require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mattn/go-runewidth v0.0.3 // indirect
+	github.com/nsf/termbox-go v0.0.0-20180819125858-b66b20ab708e // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.2.2
 )
@@ -0,0 +1,192 @@
+package httplog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/BellerophonMobile/logberry"
+)
+
+// Handler returns an http.Handler logging each request to next as a
+// sub-task of the given parent Task.  The request Task reports the
+// method, path, and remote address, and is stored in the request's
+// context, retrievable via logberry.FromContext.  It concludes with a
+// success event reporting the status code and response size, or an
+// error event if the status code indicates a server error.  Panics
+// in next are recovered, reported as an error event, and answered
+// with an internal server error if no response has been written.  An
+// incoming traceparent header makes the request Task the remote child
+// of the calling span; otherwise it begins a new trace.
+func Handler(parent *logberry.Task, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		remote, _ := logberry.Extract(r.Header)
+
+		task := parent.RemoteTask("HTTP request", remote, logberry.D{
+			"Method":     r.Method,
+			"Path":       r.URL.Path,
+			"RemoteAddr": r.RemoteAddr,
+		})
+
+		rw := &ResponseWriter{ResponseWriter: w}
+
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					task.Failure("Handler aborted", rw.data())
+					panic(p)
+				}
+
+				if !rw.wroteheader {
+					http.Error(rw, http.StatusText(http.StatusInternalServerError),
+						http.StatusInternalServerError)
+				}
+
+				task.Error(logberry.NewError("Handler panicked",
+					logberry.D{"Panic": fmt.Sprint(p)}), rw.data())
+				return
+			}
+
+			if rw.Status() >= 500 {
+				task.Failure("Server error", rw.data())
+			} else {
+				task.Success(rw.data())
+			}
+		}()
+
+		next.ServeHTTP(rw.wrap(), r.WithContext(logberry.WithTask(r.Context(), task)))
+
+	})
+}
+
+// Middleware returns a function wrapping handlers via Handler, for use
+// with routers accepting middleware in that form.
+func Middleware(parent *logberry.Task) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Handler(parent, next)
+	}
+}
+
+// ResponseWriter wraps an http.ResponseWriter to capture the status
+// code and number of bytes of the response.  It implements the
+// optional http.Flusher, http.Pusher, and io.ReaderFrom interfaces,
+// passing through to the underlying writer where it does.  Handler
+// also passes an http.Hijacker to the next handler if the underlying
+// writer is one, e.g., for websocket upgrades.
+type ResponseWriter struct {
+	http.ResponseWriter
+
+	status      int
+	bytes       int64
+	wroteheader bool
+}
+
+// WriteHeader records the status code and passes it through.
+func (x *ResponseWriter) WriteHeader(status int) {
+	if !x.wroteheader {
+		x.status = status
+		x.wroteheader = true
+	}
+	x.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written and passes them through.
+func (x *ResponseWriter) Write(b []byte) (int, error) {
+	if !x.wroteheader {
+		x.WriteHeader(http.StatusOK)
+	}
+	n, err := x.ResponseWriter.Write(b)
+	x.bytes += int64(n)
+	return n, err
+}
+
+// Flush passes through to the underlying writer if it is an
+// http.Flusher.
+func (x *ResponseWriter) Flush() {
+	if f, ok := x.ResponseWriter.(http.Flusher); ok {
+		if !x.wroteheader {
+			x.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Push passes through to the underlying writer if it is an
+// http.Pusher, returning http.ErrNotSupported otherwise.
+func (x *ResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := x.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// ReadFrom records the number of bytes copied from the reader, passing
+// through to the underlying writer if it is an io.ReaderFrom.
+func (x *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !x.wroteheader {
+		x.WriteHeader(http.StatusOK)
+	}
+
+	if rf, ok := x.ResponseWriter.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(r)
+		x.bytes += n
+		return n, err
+	}
+
+	// Hide this method from io.Copy, which would otherwise recurse
+	return io.Copy(struct{ io.Writer }{x}, r)
+}
+
+// hijacker is a ResponseWriter exposing the http.Hijacker interface of
+// the writer it wraps.
+type hijacker struct {
+	*ResponseWriter
+}
+
+// Hijack passes through to the underlying writer, after which the
+// response is recorded as switching protocols if no status had been
+// written.
+func (x hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := x.ResponseWriter.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && !x.wroteheader {
+		x.status = http.StatusSwitchingProtocols
+		x.wroteheader = true
+	}
+	return conn, rw, err
+}
+
+// wrap returns the writer to pass to handlers, exposing http.Hijacker
+// only if the underlying writer implements it.
+func (x *ResponseWriter) wrap() http.ResponseWriter {
+	if _, ok := x.ResponseWriter.(http.Hijacker); ok {
+		return hijacker{x}
+	}
+	return x
+}
+
+// Unwrap returns the underlying writer, e.g., for use by
+// http.ResponseController.
+func (x *ResponseWriter) Unwrap() http.ResponseWriter {
+	return x.ResponseWriter
+}
+
+// Status returns the status code of the response, which is
+// http.StatusOK if none has been explicitly written.
+func (x *ResponseWriter) Status() int {
+	if x.status == 0 {
+		return http.StatusOK
+	}
+	return x.status
+}
+
+// Bytes returns the number of bytes of response body written.
+func (x *ResponseWriter) Bytes() int64 {
+	return x.bytes
+}
+
+func (x *ResponseWriter) data() logberry.D {
+	return logberry.D{"Status": x.Status(), "Bytes": x.bytes}
+}
@@ -0,0 +1,120 @@
+package httplog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BellerophonMobile/logberry"
+	"github.com/BellerophonMobile/logberry/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Handler(t *testing.T) {
+	require := require.New(t)
+
+	root, capture := tests.NewCaptureRoot()
+	server := root.Component("server")
+
+	var handlertask *logberry.Task
+	h := Handler(server, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlertask = logberry.FromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}))
+
+	req := httptest.NewRequest("GET", "/pot", nil)
+	req.Header.Set(logberry.TraceparentHeader,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	root.Stop()
+
+	require.Equal(http.StatusTeapot, rec.Code)
+
+	begin, end := capture.Events()[1], capture.Events()[2]
+	require.Equal(handlertask.ID(), begin.TaskID)
+	require.Equal(logberry.EventDataString("/pot"), begin.Data["Path"])
+	require.Equal("4bf92f3577b34da6a3ce929d0e0e4736", begin.TraceID)
+	require.Equal("00f067aa0ba902b7", begin.ParentSpanID)
+
+	require.Equal(logberry.SUCCESS, end.Event)
+	require.Equal(logberry.EventDataInt64(http.StatusTeapot), end.Data["Status"])
+	require.Equal(logberry.EventDataInt64(15), end.Data["Bytes"])
+	require.True(end.Duration > 0)
+
+}
+
+func Test_Handler_Panic(t *testing.T) {
+	require := require.New(t)
+
+	root, capture := tests.NewCaptureRoot()
+
+	h := Handler(root.Component("server"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("Handler failure")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	root.Stop()
+
+	require.Equal(http.StatusInternalServerError, rec.Code)
+
+	end := capture.Events()[len(capture.Events())-1]
+	require.Equal(logberry.ERROR, end.Event)
+	require.Equal(logberry.EventDataInt64(http.StatusInternalServerError), end.Data["Status"])
+
+}
+
+type hijackrecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (x *hijackrecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	x.hijacked = true
+	return nil, nil, nil
+}
+
+func Test_Handler_Interfaces(t *testing.T) {
+	require := require.New(t)
+
+	root, capture := tests.NewCaptureRoot()
+	server := root.Component("server")
+
+	h := Handler(server, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/upgrade" {
+			_, _, err := w.(http.Hijacker).Hijack()
+			require.Nil(err)
+			return
+		}
+
+		_, ok := w.(http.Hijacker)
+		require.False(ok)
+		require.Equal(http.ErrNotSupported, w.(http.Pusher).Push("/style.css", nil))
+
+		n, err := io.Copy(w, strings.NewReader("copied"))
+		require.Nil(err)
+		require.Equal(int64(6), n)
+	}))
+
+	rec := &hijackrecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/upgrade", nil))
+	require.True(rec.hijacked)
+
+	plain := httptest.NewRecorder()
+	h.ServeHTTP(plain, httptest.NewRequest("GET", "/copy", nil))
+	require.Equal("copied", plain.Body.String())
+
+	root.Stop()
+
+	upgrade, copied := capture.Events()[2], capture.Events()[4]
+	require.Equal(logberry.EventDataInt64(http.StatusSwitchingProtocols), upgrade.Data["Status"])
+	require.Equal(logberry.EventDataInt64(6), copied.Data["Bytes"])
+
+}
@@ -0,0 +1,116 @@
+package httplog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/BellerophonMobile/logberry"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object.  The Logberry Error
+// underlying the problem, if any, is carried in full as the "error"
+// extension member, such that the receiver may reconstruct it.
+type Problem struct {
+	Type     string          `json:"type,omitempty"`
+	Title    string          `json:"title,omitempty"`
+	Status   int             `json:"status,omitempty"`
+	Detail   string          `json:"detail,omitempty"`
+	Instance string          `json:"instance,omitempty"`
+	Error    *logberry.Error `json:"error,omitempty"`
+}
+
+// NewProblem returns a Problem describing the given error with the
+// given status code.  If zero, the status is that registered for the
+// error's code, as by logberry.ErrorStatus, and the registered URL, if
+// any, is given as the problem type.  The Error is the first Logberry
+// Error in err's chain, as found by errors.As.
+func NewProblem(status int, err error) *Problem {
+
+	p := &Problem{}
+
+	if status == 0 {
+		status = logberry.ErrorStatus(err)
+		if c := logberry.ErrorCodeOf(err); c != nil {
+			p.Type = c.URL
+		}
+	}
+
+	p.Title = http.StatusText(status)
+	p.Status = status
+
+	if err != nil {
+		p.Detail = fmt.Sprint(err)
+		errors.As(err, &p.Error)
+	}
+
+	return p
+
+}
+
+// WriteProblem writes the given error to w as a problem+json response
+// with the given status code, as built by NewProblem.  The Error is
+// included with its data, source positions, and cause chain, so this
+// should only be used to answer trusted clients, e.g., other services
+// of the same system.
+func WriteProblem(w http.ResponseWriter, status int, err error) error {
+
+	p := NewProblem(status, err)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+
+	return json.NewEncoder(w).Encode(p)
+
+}
+
+// ReadProblem reconstructs the error reported by a response.  If the
+// response is a problem+json document carrying a Logberry Error, as
+// written by WriteProblem, that Error is returned with its original
+// structure.  Otherwise an Error is built from the problem details,
+// or for other responses just from the status.  The returned error is
+// non-nil only if a problem+json body could not be decoded.  The body
+// is not closed.
+func ReadProblem(resp *http.Response) (*logberry.Error, error) {
+
+	mediatype, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediatype != ProblemContentType {
+		return &logberry.Error{
+			Message: resp.Status,
+			Data:    logberry.EventDataMap{"Status": logberry.EventDataInt64(resp.StatusCode)},
+		}, nil
+	}
+
+	var p Problem
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, logberry.WrapError("Could not decode problem", err,
+			logberry.D{"Status": resp.StatusCode})
+	}
+
+	if p.Error != nil {
+		return p.Error, nil
+	}
+
+	msg := p.Detail
+	if msg == "" {
+		msg = p.Title
+	}
+	if msg == "" {
+		msg = resp.Status
+	}
+
+	return &logberry.Error{
+		Message: msg,
+		Data: logberry.Aggregate([]interface{}{logberry.D{
+			"Type":     p.Type,
+			"Status":   p.Status,
+			"Instance": p.Instance,
+		}}),
+	}, nil
+
+}
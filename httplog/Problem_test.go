@@ -0,0 +1,94 @@
+package httplog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BellerophonMobile/logberry"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Problem(t *testing.T) {
+	require := require.New(t)
+
+	base := errors.New("no such row")
+	lerr := logberry.WrapError("User not found", base, logberry.D{"User": 7}).SetCode("not-found")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteProblem(w, http.StatusNotFound, fmt.Errorf("loading profile: %w", lerr))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.Nil(err)
+	defer resp.Body.Close()
+
+	require.Equal(http.StatusNotFound, resp.StatusCode)
+	require.Equal(ProblemContentType, resp.Header.Get("Content-Type"))
+
+	remote, err := ReadProblem(resp)
+	require.Nil(err)
+	require.Equal("User not found", remote.Message)
+	require.Equal("not-found", remote.Code)
+	require.Equal(logberry.EventDataMap{"User": logberry.EventDataInt64(7)}, remote.Data)
+	require.Equal(*lerr.Source, *remote.Source)
+	require.Equal("no such row", remote.Cause.Error())
+	require.True(logberry.IsError(remote, "not-found"))
+
+}
+
+func Test_Problem_Plain(t *testing.T) {
+	require := require.New(t)
+
+	rec := httptest.NewRecorder()
+	require.Nil(WriteProblem(rec, 0, errors.New("disk full")))
+	require.Equal(http.StatusInternalServerError, rec.Code)
+	require.Contains(rec.Body.String(), `"detail":"disk full"`)
+	require.NotContains(rec.Body.String(), `"error"`)
+
+	remote, err := ReadProblem(rec.Result())
+	require.Nil(err)
+	require.Equal("disk full", remote.Message)
+	require.Equal(logberry.EventDataInt64(500), remote.Data["Status"])
+
+	resp := &http.Response{
+		Status:     "502 Bad Gateway",
+		StatusCode: http.StatusBadGateway,
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		Body:       http.NoBody,
+	}
+	remote, err = ReadProblem(resp)
+	require.Nil(err)
+	require.Equal("502 Bad Gateway", remote.Message)
+
+	resp.Header.Set("Content-Type", ProblemContentType+"; charset=utf-8")
+	resp.Body = http.NoBody
+	_, err = ReadProblem(resp)
+	require.NotNil(err)
+
+}
+
+var testgone = logberry.RegisterCode(logberry.ErrorCode{
+	Code:    "httplog-test-gone",
+	Message: "Resource {ID} deleted",
+	Status:  http.StatusGone,
+	URL:     "https://example.com/errors/gone",
+})
+
+func Test_Problem_Code(t *testing.T) {
+	require := require.New(t)
+
+	p := NewProblem(0, testgone.New(logberry.D{"ID": "a1"}))
+	require.Equal(http.StatusGone, p.Status)
+	require.Equal("https://example.com/errors/gone", p.Type)
+	require.Equal("Gone", p.Title)
+	require.Equal("Resource a1 deleted <httplog-test-gone>", p.Detail)
+
+	p = NewProblem(http.StatusBadRequest, testgone.New())
+	require.Equal(http.StatusBadRequest, p.Status)
+	require.Equal("", p.Type)
+
+}
@@ -0,0 +1,131 @@
+package httplog
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/BellerophonMobile/logberry"
+)
+
+// Redacted replaces the values of redacted headers and query
+// parameters in logged data.
+const Redacted = "<!redacted!>"
+
+// Transport is an http.RoundTripper logging each outbound request as a
+// Task.  The Task is created as a sub-task of that carried by the
+// request's context, or logberry.Main if there is none, reporting the
+// method and URL.  It concludes with a success event reporting the
+// status code and response size, or an error event if the request
+// failed or the status code indicates a server error.  The request
+// Task's trace context is injected into the request's traceparent
+// headers, beginning a new trace if the parent Task is not traced.
+// Errors from the base RoundTripper are logged but returned as they
+// are, such that callers may still inspect them, e.g., for timeouts.
+// The request passed to the base RoundTripper is a clone of the
+// original, which is not modified.
+type Transport struct {
+	// Base is the RoundTripper actually making requests.  If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Headers includes the request headers in the logged data if true.
+	Headers bool
+
+	// RedactHeaders lists request headers whose values are not logged.
+	// Authorization, Cookie, and Proxy-Authorization are always
+	// redacted.
+	RedactHeaders []string
+
+	// RedactQuery lists URL query parameters whose values are not
+	// logged.  Passwords in the URL's user info are always redacted.
+	RedactQuery []string
+}
+
+var alwaysredacted = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// RoundTrip executes and logs a single HTTP transaction.
+func (x *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	base := x.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	d := logberry.D{
+		"Method": req.Method,
+		"URL":    x.redacturl(req.URL),
+	}
+
+	if x.Headers {
+		d["Headers"] = x.redactheaders(req.Header)
+	}
+
+	parent := logberry.FromContext(req.Context())
+
+	var task *logberry.Task
+	if parent.TraceContext().IsValid() {
+		task = parent.Task("HTTP client request", d)
+	} else {
+		task = parent.RemoteTask("HTTP client request", logberry.TraceContext{}, d)
+	}
+
+	outbound := req.Clone(logberry.WithTask(req.Context(), task))
+	task.TraceContext().Inject(outbound.Header)
+
+	resp, err := base.RoundTrip(outbound)
+	if err != nil {
+		task.Error(err)
+		return nil, err
+	}
+
+	result := logberry.D{"Status": resp.StatusCode}
+	if resp.ContentLength >= 0 {
+		result["Bytes"] = resp.ContentLength
+	}
+
+	if resp.StatusCode >= 500 {
+		task.Failure("Server error", result)
+	} else {
+		task.Success(result)
+	}
+
+	return resp, nil
+
+}
+
+func (x *Transport) redacturl(u *url.URL) string {
+
+	r := *u
+
+	if len(x.RedactQuery) > 0 && r.RawQuery != "" {
+		q := r.Query()
+		for _, k := range x.RedactQuery {
+			if _, ok := q[k]; ok {
+				q.Set(k, Redacted)
+			}
+		}
+		r.RawQuery = q.Encode()
+	}
+
+	return r.Redacted()
+
+}
+
+func (x *Transport) redactheaders(header http.Header) logberry.D {
+
+	d := logberry.D{}
+	for k, v := range header {
+		d[k] = strings.Join(v, ", ")
+	}
+
+	for _, k := range append(alwaysredacted, x.RedactHeaders...) {
+		k = http.CanonicalHeaderKey(k)
+		if _, ok := d[k]; ok {
+			d[k] = Redacted
+		}
+	}
+
+	return d
+
+}
@@ -0,0 +1,129 @@
+package httplog
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BellerophonMobile/logberry"
+	"github.com/BellerophonMobile/logberry/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Transport(t *testing.T) {
+	require := require.New(t)
+
+	root, capture := tests.NewCaptureRoot()
+	client := root.Component("client")
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(logberry.TraceparentHeader)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	c := &http.Client{
+		Transport: &Transport{
+			Headers:       true,
+			RedactHeaders: []string{"x-api-key"},
+			RedactQuery:   []string{"token"},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(logberry.WithTask(context.Background(), client),
+		"GET", server.URL+"/path?token=secret&page=2", nil)
+	require.Nil(err)
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("Accept", "text/plain")
+
+	resp, err := c.Do(req)
+	require.Nil(err)
+	resp.Body.Close()
+
+	require.Equal("", req.Header.Get(logberry.TraceparentHeader))
+	root.Stop()
+
+	begin, end := capture.Events()[1], capture.Events()[2]
+	require.Equal(logberry.EventDataString("GET"), begin.Data["Method"])
+	require.NotContains(begin.Data.String(), "secret")
+	require.Contains(begin.Data.String(), "page=2")
+	require.Equal(logberry.EventDataString("text/plain"),
+		begin.Data["Headers"].(logberry.EventDataMap)["Accept"])
+
+	tc, err := logberry.ParseTraceparent(traceparent)
+	require.Nil(err)
+	require.Equal(begin.TraceID, tc.TraceID.String())
+	require.Equal(begin.SpanID, tc.SpanID.String())
+
+	require.Equal(logberry.SUCCESS, end.Event)
+	require.Equal(logberry.EventDataInt64(200), end.Data["Status"])
+
+}
+
+type failingtransport struct{}
+
+func (x failingtransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+type timeouterror struct{}
+
+func (x timeouterror) Error() string   { return "timed out" }
+func (x timeouterror) Timeout() bool   { return true }
+func (x timeouterror) Temporary() bool { return true }
+
+type timeouttransport struct{}
+
+func (x timeouttransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, timeouterror{}
+}
+
+func Test_Transport_Error(t *testing.T) {
+	require := require.New(t)
+
+	root, capture := tests.NewCaptureRoot()
+	client := root.Component("client")
+
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req = req.WithContext(logberry.WithTask(req.Context(), client))
+
+	_, err := (&Transport{Base: failingtransport{}}).RoundTrip(req)
+	require.NotNil(err)
+
+	require.Equal("connection refused", err.Error())
+
+	root.Stop()
+
+	end := capture.Events()[len(capture.Events())-1]
+	require.Equal(logberry.ERROR, end.Event)
+
+}
+
+func Test_Transport_Timeout(t *testing.T) {
+	require := require.New(t)
+
+	root, capture := tests.NewCaptureRoot()
+	client := root.Component("client")
+
+	req, err := http.NewRequestWithContext(logberry.WithTask(context.Background(), client),
+		"GET", "http://example.com/", nil)
+	require.Nil(err)
+
+	c := &http.Client{Transport: &Transport{Base: timeouttransport{}}}
+	_, err = c.Do(req)
+	require.NotNil(err)
+
+	nerr, ok := err.(net.Error)
+	require.True(ok)
+	require.True(nerr.Timeout())
+
+	root.Stop()
+
+	end := capture.Events()[len(capture.Events())-1]
+	require.Equal(logberry.ERROR, end.Event)
+
+}
@@ -0,0 +1,9 @@
+/*
+Package httplog integrates Logberry with net/http.  It provides
+middleware logging each request to a server as a Task, and a
+RoundTripper logging each outbound request of a client as a Task.
+Both propagate distributed trace context via the W3C traceparent
+headers.  Logberry Errors may also be passed between services as
+problem+json responses, via WriteProblem and ReadProblem.
+*/
+package httplog
@@ -25,6 +25,7 @@ package logberry
 import (
 	"os"
 	"path"
+	"time"
 )
 
 // Std is the default Root created at startup.
@@ -43,10 +44,11 @@ func init() {
 
 	//-- Construct the standard default task manually so no event
 	Main = &Task{
-		uid:       newtaskuid(),
+		uid:       defaultids.NewID(),
 		component: "main",
 		activity:  "Component main",
 		root:      Std,
+		start:     time.Now(),
 	}
 
 }
@@ -0,0 +1,207 @@
+/*
+Package sloghandler implements a log/slog Handler that generates
+Logberry events, so that packages logging via slog share the output
+drivers and structure of those using Logberry directly.
+*/
+package sloghandler
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/BellerophonMobile/logberry"
+)
+
+// Handler is a slog.Handler generating events on a Logberry Task.
+// Record levels map to event classes: errors to logberry.ERROR,
+// warnings to logberry.WARNING, info to logberry.INFO, debug to
+// logberry.DEBUG, and anything below debug to logberry.TRACE.  Record
+// attributes become the event data, with groups as nested maps.
+// Attributes added via WithAttrs are reported with every event, as is
+// a Task's permanent data.
+type Handler struct {
+	task   *logberry.Task
+	data   logberry.D
+	groups []string
+}
+
+// New creates a Handler generating events on the given Task.  If the
+// context passed with a record carries a Task, as set by
+// logberry.WithTask, events are generated on that Task instead.
+func New(task *logberry.Task) *Handler {
+	return &Handler{
+		task: task,
+		data: logberry.D{},
+	}
+}
+
+// Class returns the Logberry event class corresponding to the given
+// slog level.
+func Class(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return logberry.ERROR
+	case level >= slog.LevelWarn:
+		return logberry.WARNING
+	case level >= slog.LevelInfo:
+		return logberry.INFO
+	case level >= slog.LevelDebug:
+		return logberry.DEBUG
+	default:
+		return logberry.TRACE
+	}
+}
+
+// Enabled reports whether the Task's Root forwards events of the
+// given level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.target(ctx).Enabled(logberry.EventLevel(Class(level)))
+}
+
+// Handle generates an event from the record.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+
+	data := clone(h.data)
+
+	if r.NumAttrs() > 0 {
+		group := open(data, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			add(group, a)
+			return true
+		})
+	}
+
+	h.target(ctx).Event(Class(r.Level), r.Message, data)
+
+	return nil
+
+}
+
+// WithAttrs returns a Handler reporting the given attributes with
+// every event, within any currently open groups.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+
+	if len(attrs) == 0 {
+		return h
+	}
+
+	data := clone(h.data)
+	group := open(data, h.groups)
+	for _, a := range attrs {
+		add(group, a)
+	}
+
+	return &Handler{
+		task:   h.task,
+		data:   data,
+		groups: h.groups,
+	}
+
+}
+
+// WithGroup returns a Handler nesting all subsequent attributes in a
+// map under the given name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+
+	if name == "" {
+		return h
+	}
+
+	groups := append(make([]string, 0, len(h.groups)+1), h.groups...)
+
+	return &Handler{
+		task:   h.task,
+		data:   h.data,
+		groups: append(groups, name),
+	}
+
+}
+
+func (h *Handler) target(ctx context.Context) *logberry.Task {
+	if ctx != nil {
+		if t, ok := logberry.TaskFromContext(ctx); ok {
+			return t
+		}
+	}
+	return h.task
+}
+
+// open returns the map nested in data under the given group path,
+// creating it as necessary.
+func open(data logberry.D, groups []string) logberry.D {
+	for _, g := range groups {
+		sub, ok := data[g].(logberry.D)
+		if !ok {
+			sub = logberry.D{}
+			data[g] = sub
+		}
+		data = sub
+	}
+	return data
+}
+
+// clone copies the nested maps of data, such that attributes may be
+// added without modifying the original.  Leaf values are shared.
+func clone(data logberry.D) logberry.D {
+	c := make(logberry.D, len(data))
+	for k, v := range data {
+		if sub, ok := v.(logberry.D); ok {
+			v = clone(sub)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+// add incorporates the attribute into data, following the slog
+// conventions of ignoring empty attributes and inlining groups
+// without keys.
+func add(data logberry.D, a slog.Attr) {
+
+	a.Value = a.Value.Resolve()
+
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		data[a.Key] = value(a.Value)
+		return
+	}
+
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+
+	group := data
+	if a.Key != "" {
+		group = open(data, []string{a.Key})
+	}
+
+	for _, ga := range attrs {
+		add(group, ga)
+	}
+
+}
+
+func value(v slog.Value) interface{} {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration()
+	case slog.KindTime:
+		return v.Time()
+	default:
+		return v.Any()
+	}
+}
@@ -0,0 +1,52 @@
+package sloghandler
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/BellerophonMobile/logberry"
+	"github.com/BellerophonMobile/logberry/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Handler(t *testing.T) {
+	require := require.New(t)
+
+	capture := &tests.CaptureOutput{}
+	root := logberry.NewSyncRoot()
+	root.AddOutputDriver(capture)
+	require.Nil(root.SetLevels("db=debug,*=info"))
+
+	db := root.Component("db")
+	logger := slog.New(New(db))
+
+	logger.Debug("Query", "Table", "users")
+	logger.Log(context.Background(), slog.LevelDebug-4, "Dropped")
+
+	logger.With("Conn", 7).WithGroup("Request").
+		Warn("Slow", "Rows", 3, slog.Group("Plan", "Index", true))
+
+	logger.Error("Failed", slog.Group("", "Inlined", "yes"), slog.Group("Empty"))
+
+	request := root.Task("Request")
+	logger.InfoContext(logberry.WithTask(context.Background(), request), "Routed")
+
+	root.Stop()
+
+	events := capture.Events()[1:]
+
+	require.Equal(logberry.DEBUG, events[0].Event)
+	require.Equal("Query", events[0].Message)
+	require.Equal(logberry.EventDataString("users"), events[0].Data["Table"])
+
+	require.Equal(logberry.WARNING, events[1].Event)
+	require.Equal(`{ Conn=7 Request={ Plan={ Index=true } Rows=3 } }`, events[1].Data.String())
+
+	require.Equal(logberry.ERROR, events[2].Event)
+	require.Equal(`{ Inlined="yes" }`, events[2].Data.String())
+
+	require.Equal(request.ID(), events[4].TaskID)
+	require.Equal("Routed", events[4].Message)
+
+}
@@ -0,0 +1,196 @@
+/*
+Package slogoutput implements a Logberry OutputDriver forwarding events
+to a log/slog Handler, so that Logberry instrumented code may feed a
+slog based pipeline without losing its task structure.
+*/
+package slogoutput
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/BellerophonMobile/logberry"
+)
+
+// LevelTrace is the slog level to which Logberry trace events map,
+// below slog.LevelDebug.
+const LevelTrace = slog.LevelDebug - 4
+
+// SlogOutput is an OutputDriver converting each event to a slog
+// Record and passing it to a slog Handler.  The record's level is
+// mapped from the event's Level and its message is the event's.  The
+// identifying fields of the event, i.e., TaskID, ParentID, RunID,
+// Component, Event, and any trace identifiers and duration, are
+// attributes of the record named as in the Event structure.  The
+// event's Data and Static maps are groups named Data and Static,
+// within which nested maps are nested groups.
+type SlogOutput struct {
+	root    *logberry.Root
+	handler slog.Handler
+}
+
+// New creates a new SlogOutput forwarding to the given Handler.
+func New(handler slog.Handler) *SlogOutput {
+	return &SlogOutput{
+		handler: handler,
+	}
+}
+
+// Attach notifies the OutputDriver of its Root.  It should only be
+// called by a Root.
+func (x *SlogOutput) Attach(root *logberry.Root) {
+	x.root = root
+}
+
+// Detach notifies the OutputDriver that it has been removed from its
+// Root.  It should only be called by a root.
+func (x *SlogOutput) Detach() {
+	x.root = nil
+}
+
+// Event outputs a generated log entry, as called by a Root or a
+// chaining OutputDriver.
+func (x *SlogOutput) Event(event *logberry.Event) {
+
+	ctx := context.Background()
+	level := Level(event.Level)
+
+	if !x.handler.Enabled(ctx, level) {
+		return
+	}
+
+	r := slog.NewRecord(event.Timestamp, level, event.Message, 0)
+
+	r.AddAttrs(
+		slog.String("TaskID", string(event.TaskID)),
+		slog.String("RunID", string(event.RunID)),
+		slog.String("Event", event.Event),
+	)
+
+	optional := []struct{ key, value string }{
+		{"ParentID", string(event.ParentID)},
+		{"Component", event.Component},
+		{"TraceID", event.TraceID},
+		{"SpanID", event.SpanID},
+		{"ParentSpanID", event.ParentSpanID},
+	}
+	for _, o := range optional {
+		if o.value != "" {
+			r.AddAttrs(slog.String(o.key, o.value))
+		}
+	}
+
+	if event.Duration > 0 {
+		r.AddAttrs(slog.Duration("Duration", event.Duration))
+	}
+
+	if len(event.Data) > 0 {
+		r.AddAttrs(slog.Attr{Key: "Data", Value: Value(event.Data)})
+	}
+
+	if len(event.Static) > 0 {
+		r.AddAttrs(slog.Attr{Key: "Static", Value: Value(event.Static)})
+	}
+
+	err := x.handler.Handle(ctx, r)
+	if err != nil && x.root != nil {
+		x.root.InternalError(logberry.WrapError("Could not handle entry", err))
+	}
+
+}
+
+// Level returns the slog level corresponding to a Logberry Level.
+func Level(level logberry.Level) slog.Level {
+	switch level {
+	case logberry.LevelTrace:
+		return LevelTrace
+	case logberry.LevelDebug:
+		return slog.LevelDebug
+	case logberry.LevelWarning:
+		return slog.LevelWarn
+	case logberry.LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Value converts event data to a slog Value.  Maps become groups,
+// with their keys in sorted order, and slices become slices of plain
+// Go values, with maps within them as map[string]interface{}, such
+// that handlers may serialize them.
+func Value(data logberry.EventData) slog.Value {
+
+	switch d := data.(type) {
+
+	case logberry.EventDataMap:
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		attrs := make([]slog.Attr, len(keys))
+		for i, k := range keys {
+			attrs[i] = slog.Attr{Key: k, Value: Value(d[k])}
+		}
+		return slog.GroupValue(attrs...)
+
+	case logberry.EventDataSlice:
+		return slog.AnyValue(plain(d))
+
+	case logberry.EventDataString:
+		return slog.StringValue(string(d))
+
+	case logberry.EventDataInt64:
+		return slog.Int64Value(int64(d))
+
+	case logberry.EventDataUInt64:
+		return slog.Uint64Value(uint64(d))
+
+	case logberry.EventDataFloat64:
+		return slog.Float64Value(float64(d))
+
+	case logberry.EventDataBool:
+		return slog.BoolValue(bool(d))
+
+	case logberry.EventDataTime:
+		return slog.TimeValue(time.Time(d))
+
+	case logberry.EventDataDuration:
+		return slog.DurationValue(time.Duration(d))
+
+	default:
+		return slog.AnyValue(data)
+
+	}
+
+}
+
+// plain converts event data within a slice to plain Go values.
+func plain(data logberry.EventData) interface{} {
+
+	switch d := data.(type) {
+
+	case logberry.EventDataMap:
+		m := make(map[string]interface{}, len(d))
+		for k, v := range d {
+			m[k] = plain(v)
+		}
+		return m
+
+	case logberry.EventDataSlice:
+		s := make([]interface{}, len(d))
+		for i, v := range d {
+			s[i] = plain(v)
+		}
+		return s
+
+	default:
+		return Value(data).Any()
+
+	}
+
+}
@@ -0,0 +1,54 @@
+package slogoutput
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/BellerophonMobile/logberry"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SlogOutput(t *testing.T) {
+	require := require.New(t)
+
+	buffer := new(bytes.Buffer)
+	handler := slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	root := logberry.NewSyncRoot()
+	root.AddOutputDriver(New(handler))
+
+	task := root.Component("db")
+	task.Trace("Dropped by handler")
+	task.Warning("Slow query", logberry.D{
+		"Rows":   3,
+		"Plan":   logberry.D{"Index": true},
+		"Tables": []string{"users", "roles"},
+		"Items":  []logberry.D{{"A": 1, "B": []logberry.D{{"C": "x"}}}},
+	})
+
+	root.Stop()
+
+	lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+	require.Len(lines, 2)
+
+	var record map[string]interface{}
+	require.Nil(json.Unmarshal(lines[1], &record))
+
+	require.Equal("WARN", record["level"])
+	require.Equal("Slow query", record["msg"])
+	require.Equal(string(task.ID()), record["TaskID"])
+	require.Equal("db", record["Component"])
+	require.Equal(logberry.WARNING, record["Event"])
+
+	data := record["Data"].(map[string]interface{})
+	require.Equal(3.0, data["Rows"])
+	require.Equal(map[string]interface{}{"Index": true}, data["Plan"])
+	require.Equal([]interface{}{"users", "roles"}, data["Tables"])
+	require.Equal([]interface{}{map[string]interface{}{
+		"A": 1.0,
+		"B": []interface{}{map[string]interface{}{"C": "x"}},
+	}}, data["Items"])
+
+}
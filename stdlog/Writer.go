@@ -0,0 +1,227 @@
+/*
+Package stdlog bridges the standard library log package to Logberry,
+turning each line written by a log.Logger into an event, so that
+third party packages logging via the standard logger produce
+structured output alongside Logberry's.
+*/
+package stdlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BellerophonMobile/logberry"
+)
+
+// Writer is an io.Writer generating an informational event on a Task
+// for each line written to it.  The header a log.Logger writes before
+// each message, as determined by its prefix and flags, is parsed into
+// the event's data: the date and time as Time, in UTC if the flags
+// include log.LUTC and otherwise local time, and the source position
+// as File and Line.  A time without a date is on January 1 of year 0.  The remainder of the line is the event
+// message.  Writer is safe for concurrent use.
+//
+// With an asynchronous Root, the line written by log.Fatal could be
+// lost as the program exits before it is output.  Fatal and Fatalf
+// may be used instead to flush the Root before exiting.  Alternatively
+// a flush timeout may be set, see SetFlushTimeout.
+type Writer struct {
+	task    *logberry.Task
+	prefix  string
+	flags   int
+	timeout time.Duration
+
+	lock   sync.Mutex
+	buffer []byte
+}
+
+// FatalFlushTimeout is the longest Fatal and Fatalf wait for the
+// events of the standard logger's Writer to be output before exiting.
+const FatalFlushTimeout = time.Second
+
+// NewWriter creates a Writer generating events on the given Task,
+// parsing lines written by a log.Logger with the given prefix and
+// flags.
+func NewWriter(task *logberry.Task, prefix string, flags int) *Writer {
+	return &Writer{
+		task:   task,
+		prefix: prefix,
+		flags:  flags,
+	}
+}
+
+// SetFlushTimeout sets the longest the Writer waits for its events to
+// be output after each write, by flushing the Task's Root.  Zero, the
+// default, disables flushing, such that writes return as soon as their
+// events are queued.  Note that flushing waits for every event of the
+// Root, not only those of the Writer, and so may greatly slow logging.
+func (x *Writer) SetFlushTimeout(timeout time.Duration) {
+	x.lock.Lock()
+	x.timeout = timeout
+	x.lock.Unlock()
+}
+
+// Flush waits until the events generated by the Writer have been
+// output, as Root.Flush on the Root of its Task.
+func (x *Writer) Flush(ctx context.Context) error {
+	return x.task.Root().Flush(ctx)
+}
+
+// Fatal is equivalent to log.Fatal, but if the standard logger writes
+// to a Writer then its Root is flushed before exiting, waiting up to
+// FatalFlushTimeout.
+func Fatal(v ...interface{}) {
+	log.Output(2, fmt.Sprint(v...))
+	exit()
+}
+
+// Fatalf is equivalent to log.Fatalf, but flushes as Fatal.
+func Fatalf(format string, v ...interface{}) {
+	log.Output(2, fmt.Sprintf(format, v...))
+	exit()
+}
+
+func exit() {
+
+	if w, ok := log.Writer().(*Writer); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), FatalFlushTimeout)
+		w.Flush(ctx)
+		cancel()
+	}
+
+	os.Exit(1)
+
+}
+
+// Redirect sets the output of the standard logger to a Writer
+// generating events on the given Task.  The standard logger's prefix
+// and flags should not be changed afterwards, or the header of its
+// lines will not be parsed correctly.
+func Redirect(task *logberry.Task) {
+	RedirectLogger(log.Default(), task)
+}
+
+// RedirectLogger sets the output of the given logger to a Writer
+// generating events on the given Task, as Redirect.
+func RedirectLogger(logger *log.Logger, task *logberry.Task) {
+	logger.SetOutput(NewWriter(task, logger.Prefix(), logger.Flags()))
+}
+
+// Write generates an event for each complete line in p.  Incomplete
+// lines are buffered until completed by subsequent writes.
+func (x *Writer) Write(p []byte) (int, error) {
+
+	x.lock.Lock()
+
+	x.buffer = append(x.buffer, p...)
+
+	lines := 0
+	for {
+		i := bytes.IndexByte(x.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := string(x.buffer[:i])
+		x.buffer = x.buffer[i+1:]
+
+		msg, d := x.Parse(line)
+		x.task.Info(msg, d)
+		lines++
+	}
+
+	if len(x.buffer) == 0 {
+		x.buffer = nil
+	}
+
+	timeout := x.timeout
+	x.lock.Unlock()
+
+	// Flushed once unlocked so that other writes need not wait
+	if lines > 0 && timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		x.Flush(ctx)
+		cancel()
+	}
+
+	return len(p), nil
+
+}
+
+// Parse separates the header of a line written by a log.Logger from
+// its message, returning the message and the data parsed from the
+// header.  Parts of the header that do not match the Writer's flags
+// are left in the message.
+func (x *Writer) Parse(line string) (string, logberry.D) {
+
+	d := logberry.D{}
+
+	msgprefix := x.flags&log.Lmsgprefix != 0
+
+	if !msgprefix {
+		line = strings.TrimPrefix(line, x.prefix)
+	}
+
+	var stamp, layout []string
+	rest := line
+
+	if x.flags&log.Ldate != 0 {
+		if len(rest) >= 11 && rest[4] == '/' && rest[7] == '/' && rest[10] == ' ' {
+			stamp = append(stamp, rest[:10])
+			layout = append(layout, "2006/01/02")
+			rest = rest[11:]
+		}
+	}
+
+	if x.flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		n, l := 8, "15:04:05"
+		if x.flags&log.Lmicroseconds != 0 {
+			n, l = 15, "15:04:05.000000"
+		}
+		if len(rest) >= n+1 && rest[2] == ':' && rest[5] == ':' && rest[n] == ' ' {
+			stamp = append(stamp, rest[:n])
+			layout = append(layout, l)
+			rest = rest[n+1:]
+		}
+	}
+
+	if len(stamp) > 0 {
+		loc := time.Local
+		if x.flags&log.LUTC != 0 {
+			loc = time.UTC
+		}
+
+		t, err := time.ParseInLocation(strings.Join(layout, " "), strings.Join(stamp, " "), loc)
+		if err == nil {
+			d["Time"] = t
+			line = rest
+		}
+	}
+
+	if x.flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if i := strings.Index(line, ": "); i > 0 {
+			pos := line[:i]
+			if j := strings.LastIndexByte(pos, ':'); j > 0 {
+				if n, err := strconv.Atoi(pos[j+1:]); err == nil {
+					d["File"] = pos[:j]
+					d["Line"] = n
+					line = line[i+2:]
+				}
+			}
+		}
+	}
+
+	if msgprefix {
+		line = strings.TrimPrefix(line, x.prefix)
+	}
+
+	return line, d
+
+}
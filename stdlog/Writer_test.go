@@ -0,0 +1,110 @@
+package stdlog
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/BellerophonMobile/logberry"
+	"github.com/BellerophonMobile/logberry/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Writer(t *testing.T) {
+	require := require.New(t)
+
+	capture := &tests.CaptureOutput{}
+	root := logberry.NewSyncRoot()
+	root.AddOutputDriver(capture)
+
+	task := root.Component("thirdparty")
+
+	logger := log.New(nil, "lib: ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+	RedirectLogger(logger, task)
+
+	logger.Printf("Connected to %v", "db")
+
+	w := NewWriter(task, "", 0)
+	w.Write([]byte("partial "))
+	w.Write([]byte("line\nsecond line\n"))
+
+	root.Stop()
+
+	require.Len(capture.Events(), 4)
+
+	e := capture.Events()[1]
+	require.Equal("Connected to db", e.Message)
+	require.Equal(logberry.EventDataString("Writer_test.go"), e.Data["File"])
+	require.Contains(e.Data, "Line")
+	stamp := time.Time(e.Data["Time"].(logberry.EventDataTime))
+	require.WithinDuration(time.Now(), stamp, time.Minute)
+
+	require.Equal("partial line", capture.Events()[2].Message)
+	require.Equal("second line", capture.Events()[3].Message)
+
+}
+
+func Test_Writer_Parse(t *testing.T) {
+	require := require.New(t)
+
+	w := NewWriter(nil, "[app] ", log.Ldate|log.Ltime|log.Llongfile|log.Lmsgprefix)
+
+	msg, d := w.Parse("2009/01/23 01:23:23 /src/main.go:12: [app] Starting")
+	require.Equal("Starting", msg)
+	require.Equal(logberry.D{
+		"Time": time.Date(2009, 1, 23, 1, 23, 23, 0, time.Local),
+		"File": "/src/main.go",
+		"Line": 12,
+	}, d)
+
+	msg, d = w.Parse("Unstructured: text")
+	require.Equal("Unstructured: text", msg)
+	require.Empty(d)
+
+	w = NewWriter(nil, "", log.Ltime|log.Lmicroseconds|log.LUTC)
+	msg, d = w.Parse("01:23:23.000042 Stamped")
+	require.Equal("Stamped", msg)
+	require.Equal(time.Date(0, 1, 1, 1, 23, 23, 42000, time.UTC), d["Time"])
+
+	msg, d = w.Parse("99:99:99.999999 Not a time")
+	require.Equal("99:99:99.999999 Not a time", msg)
+	require.Empty(d)
+
+}
+
+type slowoutput struct {
+	tests.CaptureOutput
+}
+
+func (x *slowoutput) Event(event *logberry.Event) {
+	time.Sleep(10 * time.Millisecond)
+	x.CaptureOutput.Event(event)
+}
+
+func Test_Writer_Flush(t *testing.T) {
+	require := require.New(t)
+
+	output := &slowoutput{}
+	root := logberry.NewRoot(8)
+	root.AddOutputDriver(output)
+
+	logger := log.New(nil, "", 0)
+	RedirectLogger(logger, root.Component("thirdparty"))
+	logger.Writer().(*Writer).SetFlushTimeout(time.Second)
+
+	// The line is output before the write returns, e.g., before exit
+	logger.Print("database unreachable")
+	require.Len(output.Events(), 2)
+	require.Equal("database unreachable", output.Events()[1].Message)
+
+	// By default lines are only output once explicitly flushed
+	w := NewWriter(root.Component("unflushed"), "", 0)
+	w.Write([]byte("queued\n"))
+	require.Nil(w.Flush(context.Background()))
+	require.Len(output.Events(), 4)
+	require.Equal("queued", output.Events()[3].Message)
+
+	root.Stop()
+
+}
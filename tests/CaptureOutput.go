@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"sync"
+
+	"github.com/BellerophonMobile/logberry"
+)
+
+// CaptureOutput is an OutputDriver recording the events it receives,
+// for inspection by tests.  It is safe for concurrent use.
+type CaptureOutput struct {
+	lock   sync.Mutex
+	events []*logberry.Event
+}
+
+// NewCaptureRoot creates a synchronous Root outputting to a new
+// CaptureOutput, such that events are recorded as soon as generated.
+func NewCaptureRoot() (*logberry.Root, *CaptureOutput) {
+	capture := &CaptureOutput{}
+	root := logberry.NewSyncRoot()
+	root.AddOutputDriver(capture)
+	return root, capture
+}
+
+func (x *CaptureOutput) Attach(root *logberry.Root) {}
+func (x *CaptureOutput) Detach()                    {}
+
+func (x *CaptureOutput) Event(event *logberry.Event) {
+	x.lock.Lock()
+	x.events = append(x.events, event)
+	x.lock.Unlock()
+}
+
+// Events returns the events recorded so far, in order.
+func (x *CaptureOutput) Events() []*logberry.Event {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	return append([]*logberry.Event(nil), x.events...)
+}
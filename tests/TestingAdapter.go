@@ -42,7 +42,7 @@ func SetStdTesting(t *testing.T) *TestingAdapter {
 		t:    t,
 	}
 
-	logberry.Std = logberry.NewRoot(24)
+	logberry.Std = logberry.NewSyncRoot()
 	logberry.Std.AddOutputDriver(logberry.NewTextOutput(adapter, "testing"))
 	logberry.Main = logberry.Std.Task("Test")
 